@@ -286,13 +286,13 @@ export function createExifSummary(exifData) {
 
     // Exposure settings
     if (exifData.ExposureTime && exifData.FNumber && exifData.ISO) {
-        const exposure = `${exifData.ExposureTime}s, f/${exifData.FNumber}, ISO ${exifData.ISO}`;
+        const exposure = `${exifData.ExposureTime}, ${exifData.FNumber}, ISO ${exifData.ISO}`;
         items.push({ label: '露出設定', value: exposure });
     }
 
     // Focal length
     if (exifData.FocalLength) {
-        items.push({ label: '焦点距離', value: exifData.FocalLength + 'mm' });
+        items.push({ label: '焦点距離', value: exifData.FocalLength });
     }
 
     // GPS
@@ -108,6 +108,80 @@ class WasmExifParser {
         return JSON.parse(jsonString);
     }
 
+    async parseMetadata(imageData) {
+        if (!this.initialized) {
+            await this.load();
+        }
+
+        if (typeof parseMetadata !== 'function') {
+            throw new Error('WASM module not initialized');
+        }
+
+        const jsonString = await parseMetadata(imageData);
+        return JSON.parse(jsonString);
+    }
+
+    async extractThumbnail(imageData) {
+        if (!this.initialized) {
+            await this.load();
+        }
+
+        if (typeof extractThumbnail !== 'function') {
+            throw new Error('WASM module not initialized');
+        }
+
+        return extractThumbnail(imageData);
+    }
+
+    async extractGainMap(imageData) {
+        if (!this.initialized) {
+            await this.load();
+        }
+
+        if (typeof extractGainMap !== 'function') {
+            throw new Error('WASM module not initialized');
+        }
+
+        return extractGainMap(imageData);
+    }
+
+    async extractMotionPhoto(imageData) {
+        if (!this.initialized) {
+            await this.load();
+        }
+
+        if (typeof extractMotionPhoto !== 'function') {
+            throw new Error('WASM module not initialized');
+        }
+
+        return extractMotionPhoto(imageData);
+    }
+
+    async extractDepthMap(imageData) {
+        if (!this.initialized) {
+            await this.load();
+        }
+
+        if (typeof extractDepthMap !== 'function') {
+            throw new Error('WASM module not initialized');
+        }
+
+        return extractDepthMap(imageData);
+    }
+
+    async setC2PATrustList(pem) {
+        if (!this.initialized) {
+            await this.load();
+        }
+
+        if (typeof setC2PATrustList !== 'function') {
+            throw new Error('WASM module not initialized');
+        }
+
+        const data = typeof pem === 'string' ? new TextEncoder().encode(pem) : pem;
+        return setC2PATrustList(data);
+    }
+
     async detectFormat(imageData) {
         if (!this.initialized) {
             await this.load();
@@ -12,6 +12,12 @@ import (
 
 func main() {
 	js.Global().Set("parseExif", js.FuncOf(parseExif))
+	js.Global().Set("parseMetadata", js.FuncOf(parseMetadata))
+	js.Global().Set("extractThumbnail", js.FuncOf(extractThumbnail))
+	js.Global().Set("extractGainMap", js.FuncOf(extractGainMap))
+	js.Global().Set("extractMotionPhoto", js.FuncOf(extractMotionPhoto))
+	js.Global().Set("extractDepthMap", js.FuncOf(extractDepthMap))
+	js.Global().Set("setC2PATrustList", js.FuncOf(setC2PATrustList))
 	js.Global().Set("detectImageFormat", js.FuncOf(detectImageFormat))
 	js.Global().Set("getSupportedFormats", js.FuncOf(getSupportedFormats))
 
@@ -19,6 +25,104 @@ func main() {
 }
 
 func parseExif(this js.Value, args []js.Value) interface{} {
+	// Legacy flat map used by the current UI
+	return parseAsync(args, func(md *parser.Metadata) interface{} {
+		return md.ExifData()
+	})
+}
+
+func parseMetadata(this js.Value, args []js.Value) interface{} {
+	// Structured result with groups, raw values and offsets
+	return parseAsync(args, func(md *parser.Metadata) interface{} {
+		return md
+	})
+}
+
+// parseAsync parses the image in args[0] and resolves a Promise with
+// the JSON encoding of view(metadata)
+func parseAsync(args []js.Value, view func(md *parser.Metadata) interface{}) interface{} {
+	return promise(args, func(data []byte) (interface{}, error) {
+		md, err := parser.ParseImage(data)
+		if err != nil {
+			return nil, err
+		}
+
+		jsonData, err := json.Marshal(view(md))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode JSON: %w", err)
+		}
+
+		return js.ValueOf(string(jsonData)), nil
+	})
+}
+
+func extractThumbnail(this js.Value, args []js.Value) interface{} {
+	// Embedded IFD1 thumbnail as a Uint8Array (JPEG, or TIFF for strip thumbnails)
+	return promise(args, func(data []byte) (interface{}, error) {
+		thumbnail, err := parser.ExtractThumbnail(data)
+		if err != nil {
+			return nil, err
+		}
+
+		jsArray := js.Global().Get("Uint8Array").New(len(thumbnail.Data))
+		js.CopyBytesToJS(jsArray, thumbnail.Data)
+		return jsArray, nil
+	})
+}
+
+func extractGainMap(this js.Value, args []js.Value) interface{} {
+	// HDR gain map JPEG (Ultra HDR, Apple, ISO 21496-1) as a Uint8Array
+	return promise(args, func(data []byte) (interface{}, error) {
+		gainMap, err := parser.ExtractGainMap(data)
+		if err != nil {
+			return nil, err
+		}
+
+		jsArray := js.Global().Get("Uint8Array").New(len(gainMap.Data))
+		js.CopyBytesToJS(jsArray, gainMap.Data)
+		return jsArray, nil
+	})
+}
+
+func extractMotionPhoto(this js.Value, args []js.Value) interface{} {
+	// Motion Photo MP4 video as a Uint8Array
+	return extractMedia(args, parser.ExtractMotionPhoto)
+}
+
+func extractDepthMap(this js.Value, args []js.Value) interface{} {
+	// Depth map image (JPEG or PNG) as a Uint8Array
+	return extractMedia(args, parser.ExtractDepthMap)
+}
+
+func setC2PATrustList(this js.Value, args []js.Value) interface{} {
+	// PEM trust anchors for C2PA signing certificates; resolves with their count
+	return promise(args, func(data []byte) (interface{}, error) {
+		count, err := parser.SetC2PATrustList(data)
+		if err != nil {
+			return nil, err
+		}
+		return js.ValueOf(count), nil
+	})
+}
+
+// extractMedia resolves a Promise with the bytes of the embedded media
+// item returned by extract
+func extractMedia(args []js.Value, extract func(data []byte) (*parser.EmbeddedMedia, error)) interface{} {
+	return promise(args, func(data []byte) (interface{}, error) {
+		media, err := extract(data)
+		if err != nil {
+			return nil, err
+		}
+
+		jsArray := js.Global().Get("Uint8Array").New(len(media.Data))
+		js.CopyBytesToJS(jsArray, media.Data)
+		return jsArray, nil
+	})
+}
+
+// promise copies the image in args[0] and resolves a Promise with the
+// result of run, or rejects it with the returned error
+func promise(args []js.Value, run func(data []byte) (interface{}, error)) interface{} {
 	handler := js.FuncOf(func(this js.Value, promiseArgs []js.Value) interface{} {
 		resolve := promiseArgs[0]
 		reject := promiseArgs[1]
@@ -40,19 +144,13 @@ func parseExif(this js.Value, args []js.Value) interface{} {
 			data := make([]byte, length)
 			js.CopyBytesToGo(data, jsArray)
 
-			exifData, err := parser.ParseImage(data)
+			result, err := run(data)
 			if err != nil {
 				reject.Invoke(js.ValueOf(err.Error()))
 				return
 			}
 
-			jsonData, err := json.Marshal(exifData)
-			if err != nil {
-				reject.Invoke(js.ValueOf("failed to encode JSON: " + err.Error()))
-				return
-			}
-
-			resolve.Invoke(js.ValueOf(string(jsonData)))
+			resolve.Invoke(result)
 		}()
 
 		return nil
@@ -61,6 +159,10 @@ func parseExif(this js.Value, args []js.Value) interface{} {
 	return js.Global().Get("Promise").New(handler)
 }
 
+// detectLength is how much of the file detectImageFormat copies; HEIF
+// and AVIF are told apart by the compatible brands later in the ftyp box
+const detectLength = 256
+
 func detectImageFormat(this js.Value, args []js.Value) interface{} {
 	if len(args) < 1 {
 		return js.ValueOf("unknown")
@@ -68,8 +170,8 @@ func detectImageFormat(this js.Value, args []js.Value) interface{} {
 
 	jsArray := args[0]
 	length := jsArray.Get("length").Int()
-	if length > 12 {
-		length = 12
+	if length > detectLength {
+		length = detectLength
 	}
 
 	data := make([]byte, length)
@@ -87,13 +189,15 @@ func detectImageFormat(this js.Value, args []js.Value) interface{} {
 		return js.ValueOf("WebP")
 	case parser.FormatHEIF:
 		return js.ValueOf("HEIF")
+	case parser.FormatAVIF:
+		return js.ValueOf("AVIF")
 	default:
 		return js.ValueOf("Unknown")
 	}
 }
 
 func getSupportedFormats(this js.Value, args []js.Value) interface{} {
-	formats := []string{"JPEG", "TIFF"}
+	formats := []string{"JPEG", "TIFF", "PNG", "WebP", "HEIF", "AVIF"}
 	jsArray := js.Global().Get("Array").New(len(formats))
 	for i, format := range formats {
 		jsArray.SetIndex(i, js.ValueOf(format))
@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Apple iOS MakerNote tags
+// The note starts with "Apple iOS\0", a 2-byte version and "MM"; the
+// big-endian IFD follows at offset 14 with offsets relative to the start
+// of the note
+const appleRunTimeInfo = 0x0003
+
+var appleTags = tagTable{
+	0x0001: {Name: "MakerNoteVersion", Type: typeSLong, Count: 1},
+	0x0004: {Name: "AEStable", Type: typeSLong, Count: 1, Format: formatEnum(map[int]string{0: "No", 1: "Yes"})},
+	0x0005: {Name: "AETarget", Type: typeSLong, Count: 1},
+	0x0006: {Name: "AEAverage", Type: typeSLong, Count: 1},
+	0x0007: {Name: "AFStable", Type: typeSLong, Count: 1, Format: formatEnum(map[int]string{0: "No", 1: "Yes"})},
+	0x0008: {Name: "AccelerationVector", Type: typeSRational, Count: 3, Format: func(value interface{}) string {
+		values := valueFloats(value)
+		if len(values) != 3 {
+			return formatTagValue(value)
+		}
+		return fmt.Sprintf("X %s, Y %s, Z %s", formatDecimal(values[0], 4), formatDecimal(values[1], 4), formatDecimal(values[2], 4))
+	}},
+	0x000A: {Name: "HDRImageType", Type: typeSLong, Count: 1, Format: formatEnum(map[int]string{
+		2: "Normal", 3: "HDR Image", 4: "Original Image",
+	})},
+	0x000B: {Name: "BurstUUID", Type: typeASCII},
+	0x000C: {Name: "FocusDistanceRange", Type: typeSRational, Count: 2, Format: func(value interface{}) string {
+		values := valueFloats(value)
+		if len(values) != 2 {
+			return formatTagValue(value)
+		}
+		near, far := values[0], values[1]
+		if near > far {
+			near, far = far, near
+		}
+		return formatDecimal(near, 2) + " - " + formatDecimal(far, 2) + " m"
+	}},
+	0x000F: {Name: "OISMode", Type: typeSLong, Count: 1},
+	0x0011: {Name: "ContentIdentifier", Type: typeASCII},
+	0x0014: {Name: "ImageCaptureType", Type: typeSLong, Count: 1, Format: formatEnum(map[int]string{
+		1: "ProRAW", 2: "Portrait", 10: "Photo", 11: "Manual Focus", 12: "Scene",
+	})},
+	0x0015: {Name: "ImageUniqueID", Type: typeASCII},
+	0x0017: {Name: "LivePhotoVideoIndex", Type: typeSLong, Count: 1},
+	0x001F: {Name: "PhotosAppFeatureFlags", Type: typeSLong, Count: 1},
+	0x0021: {Name: "HDRHeadroom", Type: typeSRational, Count: 1, Format: func(value interface{}) string {
+		v, ok := firstFloat(value)
+		if !ok {
+			return ""
+		}
+		return formatDecimal(v, 4)
+	}},
+	0x002B: {Name: "PhotoIdentifier", Type: typeASCII},
+	0x002E: {Name: "SignalToNoiseRatio", Type: typeSRational, Count: 1},
+}
+
+// appleRunTimeFlags names the bits of the CMTime flags in RunTimeInfo
+var appleRunTimeFlags = []struct {
+	bit  int
+	name string
+}{
+	{0x01, "Valid"},
+	{0x02, "Has been rounded"},
+	{0x04, "Positive infinity"},
+	{0x08, "Negative infinity"},
+	{0x10, "Indefinite"},
+}
+
+// parseAppleMakerNote decodes an Apple iOS MakerNote
+func parseAppleMakerNote(p *SimpleExifParser, r *tiffReader, offset, size int) {
+	note := r.data[offset : offset+size]
+	if len(note) < 16 || string(note[:10]) != "Apple iOS\x00" || string(note[12:14]) != "MM" {
+		return
+	}
+
+	ar := r.embedded(offset, offset+size, binary.BigEndian)
+	p.parseMakerNoteIFD(ar, 14, GroupApple, appleTags, func(tag uint16, raw interface{}, entryOffset int) bool {
+		if tag != appleRunTimeInfo {
+			return false
+		}
+		if data, ok := raw.([]byte); ok {
+			reportAppleRunTime(ar, data, entryOffset)
+		}
+		return true
+	})
+}
+
+// reportAppleRunTime reports the RunTimeInfo binary plist, a CMTime
+// dictionary (flags, value, epoch, timescale) holding the time since the
+// device was powered up
+func reportAppleRunTime(r *tiffReader, data []byte, entryOffset int) {
+	plist, err := parseBinaryPlist(data)
+	if err != nil {
+		return
+	}
+	dict, ok := plist.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if flags, ok := dict["flags"].(int64); ok {
+		var names []string
+		for _, f := range appleRunTimeFlags {
+			if int(flags)&f.bit != 0 {
+				names = append(names, f.name)
+			}
+		}
+		value := strings.Join(names, ", ")
+		if value == "" {
+			value = "None"
+		}
+		r.set(GroupApple, appleRunTimeInfo, "RunTimeFlags", []int32{int32(flags)}, value, entryOffset)
+	}
+
+	epoch, hasEpoch := dict["epoch"].(int64)
+	if hasEpoch {
+		r.set(GroupApple, appleRunTimeInfo, "RunTimeEpoch", []int32{int32(epoch)}, fmt.Sprintf("%d", epoch), entryOffset)
+	}
+	scale, hasScale := dict["timescale"].(int64)
+	if hasScale {
+		r.set(GroupApple, appleRunTimeInfo, "RunTimeScale", []int32{int32(scale)}, fmt.Sprintf("%d", scale), entryOffset)
+	}
+	value, hasValue := dict["value"].(int64)
+	if hasValue {
+		r.set(GroupApple, appleRunTimeInfo, "RunTimeValue", fmt.Sprintf("%d", value), fmt.Sprintf("%d", value), entryOffset)
+	}
+	if hasValue && hasScale && scale > 0 {
+		seconds := value / scale
+		uptime := fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+		r.set(GroupApple, appleRunTimeInfo, "RunTimeSincePowerUp", uptime, uptime, entryOffset)
+	}
+}
@@ -0,0 +1,29 @@
+package parser
+
+import "fmt"
+
+// AVIFParser handles AVIF format images
+// AVIF shares the ISOBMFF/HEIF container, so this reuses HEIFParser
+// and reports container information in the AVIF group
+type AVIFParser struct {
+	HEIFParser
+}
+
+// Parse extracts EXIF/metadata from AVIF images
+func (p *AVIFParser) Parse(data []byte) (*Metadata, error) {
+	md := NewMetadata(FormatAVIF)
+	if err := p.parseContainer(data, md, GroupAVIF); err != nil {
+		return nil, err
+	}
+
+	if md.Len() == 0 {
+		return nil, fmt.Errorf("no metadata found in AVIF file")
+	}
+
+	return md, nil
+}
+
+// SupportsFormat checks if this parser supports the given format
+func (p *AVIFParser) SupportsFormat(format ImageFormat) bool {
+	return format == FormatAVIF
+}
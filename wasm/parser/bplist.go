@@ -0,0 +1,210 @@
+package parser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unicode/utf16"
+)
+
+// maxPlistDepth bounds nested arrays/dictionaries in a binary plist
+const maxPlistDepth = 16
+
+// binaryPlist is a decoded "bplist00" trailer and object table
+type binaryPlist struct {
+	data        []byte
+	offsetSize  int
+	refSize     int
+	offsetTable int
+	numObjects  int
+
+	// Objects may be referenced many times; each is decoded once.
+	// decoding marks the objects being decoded to detect cycles
+	decoded  map[int]interface{}
+	decoding map[int]bool
+}
+
+// parseBinaryPlist decodes the top object of a binary property list.
+// Dictionaries become map[string]interface{}, arrays []interface{},
+// integers int64, reals and dates float64, data []byte
+func parseBinaryPlist(data []byte) (interface{}, error) {
+	if len(data) < 40 || string(data[:8]) != "bplist00" {
+		return nil, fmt.Errorf("not a binary plist")
+	}
+
+	trailer := data[len(data)-32:]
+	pl := &binaryPlist{
+		data:        data,
+		offsetSize:  int(trailer[6]),
+		refSize:     int(trailer[7]),
+		numObjects:  int(binary.BigEndian.Uint64(trailer[8:16])),
+		offsetTable: int(binary.BigEndian.Uint64(trailer[24:32])),
+		decoded:     make(map[int]interface{}),
+		decoding:    make(map[int]bool),
+	}
+	top := int(binary.BigEndian.Uint64(trailer[16:24]))
+	if pl.offsetSize < 1 || pl.offsetSize > 8 || pl.refSize < 1 || pl.refSize > 8 ||
+		pl.numObjects < 0 || pl.numObjects > len(data) || pl.offsetTable < 0 ||
+		pl.offsetTable+pl.numObjects*pl.offsetSize > len(data) {
+		return nil, fmt.Errorf("invalid binary plist trailer")
+	}
+
+	return pl.object(top, 0)
+}
+
+// readUint reads a big-endian unsigned integer of n bytes at pos
+func (pl *binaryPlist) readUint(pos, n int) (uint64, bool) {
+	if pos < 0 || n < 1 || n > 8 || pos+n > len(pl.data) {
+		return 0, false
+	}
+	var v uint64
+	for _, b := range pl.data[pos : pos+n] {
+		v = v<<8 | uint64(b)
+	}
+	return v, true
+}
+
+// object returns the object with the given index, decoding it on first use
+func (pl *binaryPlist) object(index, depth int) (interface{}, error) {
+	if index < 0 || index >= pl.numObjects || depth > maxPlistDepth {
+		return nil, fmt.Errorf("invalid plist object reference")
+	}
+	if v, ok := pl.decoded[index]; ok {
+		return v, nil
+	}
+	if pl.decoding[index] {
+		return nil, fmt.Errorf("plist reference cycle at object %d", index)
+	}
+	pl.decoding[index] = true
+	v, err := pl.decode(index, depth)
+	delete(pl.decoding, index)
+	if err != nil {
+		return nil, err
+	}
+	pl.decoded[index] = v
+	return v, nil
+}
+
+// decode decodes the object with the given index
+func (pl *binaryPlist) decode(index, depth int) (interface{}, error) {
+	offset, _ := pl.readUint(pl.offsetTable+index*pl.offsetSize, pl.offsetSize)
+	pos := int(offset)
+	if pos < 8 || pos >= len(pl.data) {
+		return nil, fmt.Errorf("invalid plist object offset")
+	}
+
+	marker := pl.data[pos]
+	kind, info := marker>>4, int(marker&0x0F)
+	pos++
+
+	switch kind {
+	case 0x0:
+		switch marker {
+		case 0x08:
+			return false, nil
+		case 0x09:
+			return true, nil
+		}
+		return nil, nil
+	case 0x1, 0x8: // integer, UID
+		n := 1 << uint(info)
+		if n > 8 {
+			// 128-bit integers keep their low 64 bits
+			pos, n = pos+n-8, 8
+		}
+		v, ok := pl.readUint(pos, n)
+		if !ok {
+			return nil, fmt.Errorf("truncated plist integer")
+		}
+		return int64(v), nil
+	case 0x2, 0x3: // real, date (seconds since 2001-01-01)
+		n := 1 << uint(info)
+		if kind == 0x3 {
+			n = 8
+		}
+		v, ok := pl.readUint(pos, n)
+		if !ok {
+			return nil, fmt.Errorf("truncated plist real")
+		}
+		if n == 4 {
+			return float64(math.Float32frombits(uint32(v))), nil
+		}
+		return math.Float64frombits(v), nil
+	}
+
+	// Remaining types carry a length, extended by an integer object
+	length := info
+	if info == 0x0F {
+		if pos >= len(pl.data) || pl.data[pos]>>4 != 0x1 {
+			return nil, fmt.Errorf("invalid plist length")
+		}
+		n := 1 << uint(pl.data[pos]&0x0F)
+		v, ok := pl.readUint(pos+1, n)
+		if !ok {
+			return nil, fmt.Errorf("truncated plist length")
+		}
+		length, pos = int(v), pos+1+n
+	}
+	if length < 0 || length > len(pl.data) {
+		return nil, fmt.Errorf("invalid plist length")
+	}
+
+	switch kind {
+	case 0x4:
+		if pos+length > len(pl.data) {
+			return nil, fmt.Errorf("truncated plist data")
+		}
+		return pl.data[pos : pos+length], nil
+	case 0x5:
+		if pos+length > len(pl.data) {
+			return nil, fmt.Errorf("truncated plist string")
+		}
+		return string(pl.data[pos : pos+length]), nil
+	case 0x6:
+		if pos+2*length > len(pl.data) {
+			return nil, fmt.Errorf("truncated plist string")
+		}
+		units := make([]uint16, length)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(pl.data[pos+2*i:])
+		}
+		return string(utf16.Decode(units)), nil
+	case 0xA:
+		out := make([]interface{}, 0, length)
+		for i := 0; i < length; i++ {
+			ref, ok := pl.readUint(pos+i*pl.refSize, pl.refSize)
+			if !ok {
+				return nil, fmt.Errorf("truncated plist array")
+			}
+			v, err := pl.object(int(ref), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case 0xD:
+		out := make(map[string]interface{}, length)
+		for i := 0; i < length; i++ {
+			keyRef, ok1 := pl.readUint(pos+i*pl.refSize, pl.refSize)
+			valueRef, ok2 := pl.readUint(pos+(length+i)*pl.refSize, pl.refSize)
+			if !ok1 || !ok2 {
+				return nil, fmt.Errorf("truncated plist dictionary")
+			}
+			key, err := pl.object(int(keyRef), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				continue
+			}
+			if out[name], err = pl.object(int(valueRef), depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("unsupported plist object 0x%02X", marker)
+}
@@ -0,0 +1,643 @@
+package parser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+	"time"
+)
+
+// C2PA (Content Credentials) manifest stores are JUMBF trees embedded in
+// JPEG APP11 segments, PNG caBX chunks, WebP C2PA chunks and ISOBMFF
+// 'uuid' boxes. The last manifest of the store is the active one: its
+// claim names the generator and lists the assertions (actions,
+// ingredients, the hash binding it to the file), and its COSE_Sign1
+// signature covers the claim with an X.509 certificate chain.
+
+// c2paBoxUUID is the extended type of the ISOBMFF box holding the store
+var c2paBoxUUID = []byte{0xD8, 0xFE, 0xC3, 0xD6, 0x1B, 0x0E, 0x48, 0x3C, 0x92, 0x97, 0x58, 0x28, 0x87, 0x7E, 0xC4, 0x81}
+
+// coseAlgorithms names the COSE signature algorithms allowed by C2PA
+var coseAlgorithms = map[int]string{
+	-7:  "ES256",
+	-35: "ES384",
+	-36: "ES512",
+	-37: "PS256",
+	-38: "PS384",
+	-39: "PS512",
+	-8:  "Ed25519",
+}
+
+// COSE header labels
+const (
+	coseHeaderAlg     = 1
+	coseHeaderX5Chain = 33
+	coseSign1Tag      = 18
+)
+
+// digitalSourceTypes describes the IPTC digital source types used in
+// c2pa.actions
+var digitalSourceTypes = map[string]string{
+	"digitalCapture":                       "Captured by a camera",
+	"negativeFilm":                         "Scanned negative film",
+	"positiveFilm":                         "Scanned positive film",
+	"print":                                "Scanned print",
+	"minorHumanEdits":                      "Minor human edits",
+	"humanEdits":                           "Human edits",
+	"compositeCapture":                     "Composite of captures",
+	"algorithmicallyEnhanced":              "Algorithmically enhanced",
+	"dataDrivenMedia":                      "Data-driven media",
+	"digitalArt":                           "Digital art",
+	"digitalCreation":                      "Digital creation",
+	"virtualRecording":                     "Virtual recording",
+	"compositeSynthetic":                   "Composite including synthetic elements",
+	"trainedAlgorithmicMedia":              "Generated by AI",
+	"compositeWithTrainedAlgorithmicMedia": "Composite including AI-generated elements",
+	"algorithmicMedia":                     "Algorithmic media",
+	"screenCapture":                        "Screen capture",
+	"composite":                            "Composite",
+}
+
+// c2paTrustAnchors are the certificates set by SetC2PATrustList
+var c2paTrustAnchors []*derCertificate
+
+// SetC2PATrustList sets the PEM-encoded certificates trusted as roots of
+// C2PA signing certificate chains and returns how many were loaded
+// Without a trust list signatures are verified but chains are not
+// An empty list clears the trust list
+func SetC2PATrustList(pemData []byte) (int, error) {
+	var anchors []*derCertificate
+	for {
+		block, rest := pem.Decode(pemData)
+		if block == nil {
+			break
+		}
+		pemData = rest
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := parseCertificate(block.Bytes)
+		if err != nil {
+			return 0, fmt.Errorf("invalid trust list certificate: %w", err)
+		}
+		anchors = append(anchors, cert)
+	}
+	if len(anchors) == 0 && len(bytes.TrimSpace(pemData)) > 0 {
+		return 0, fmt.Errorf("no PEM certificates found in trust list")
+	}
+	c2paTrustAnchors = anchors
+	return len(anchors), nil
+}
+
+// reportC2PABox reports the manifest store of an ISOBMFF C2PA box
+// The box payload holds version/flags, a purpose string and, for the
+// "manifest" purpose, the offset of a Merkle box before the store
+func reportC2PABox(md *Metadata, file []byte, box isoBox) {
+	c := &byteCursor{data: box.Payload}
+	c.skip(4)
+	if c.err != nil {
+		return
+	}
+	end := bytes.IndexByte(box.Payload[c.pos:], 0)
+	if end < 0 {
+		return
+	}
+	purpose := string(box.Payload[c.pos : c.pos+end])
+	c.skip(end + 1)
+	if purpose != "manifest" {
+		return
+	}
+	c.skip(8)
+	if c.err != nil {
+		return
+	}
+	reportC2PA(md, file, box.Payload[c.pos:], box.PayloadOffset+c.pos, "uuid")
+}
+
+// reportC2PA reports the C2PA manifest store in store, found at offset in
+// file. file is nil for embedded images, whose hash binding exclusions
+// cannot be located. Other JUMBF boxes are ignored
+func reportC2PA(md *Metadata, file []byte, store []byte, offset int, source string) {
+	root, err := readJUMBF(store, offset)
+	if err != nil {
+		md.Set(GroupErrors, "C2PA_ParseError", err.Error(), offset, source)
+		return
+	}
+	if root.Type != "c2pa" {
+		return
+	}
+
+	// Standard (c2ma) and update (c2um) manifests
+	var manifests []*jumbfBox
+	for _, c := range root.Children {
+		if c.Type == "c2ma" || c.Type == "c2um" {
+			manifests = append(manifests, c)
+		}
+	}
+	md.Set(GroupC2PA, "ManifestStore", fmt.Sprintf("%d manifests, %d bytes", len(manifests), len(store)), offset, source)
+	if len(manifests) == 0 {
+		return
+	}
+	active := manifests[len(manifests)-1]
+	md.Set(GroupC2PA, "ActiveManifest", active.Label, active.Offset, source)
+
+	if err := reportC2PAManifest(md, file, active, source); err != nil {
+		md.Set(GroupErrors, "C2PA_ParseError", err.Error(), active.Offset, source)
+	}
+}
+
+// reportC2PAManifest reports the claim, assertions and signature of a
+// manifest
+func reportC2PAManifest(md *Metadata, file []byte, manifest *jumbfBox, source string) error {
+	claimBox := manifest.child("c2pa.claim.v2")
+	if claimBox == nil {
+		claimBox = manifest.child("c2pa.claim")
+	}
+	if claimBox == nil {
+		return fmt.Errorf("manifest %s has no claim", manifest.Label)
+	}
+	claim, claimBytes, err := claimBox.cbor()
+	if err != nil {
+		return fmt.Errorf("invalid claim: %w", err)
+	}
+
+	if generator := c2paClaimGenerator(claim); generator != "" {
+		md.Set(GroupC2PA, "ClaimGenerator", generator, claimBox.Offset, source)
+	}
+	for _, field := range []struct{ key, name string }{
+		{"dc:title", "Title"},
+		{"title", "Title"},
+		{"dc:format", "Format"},
+		{"instanceID", "InstanceID"},
+	} {
+		if value := cborString(cborMapValue(claim, field.key)); value != "" {
+			md.Set(GroupC2PA, field.name, value, claimBox.Offset, source)
+		}
+	}
+	alg := cborString(cborMapValue(claim, "alg"))
+	if alg == "" {
+		alg = "sha256"
+	}
+
+	// Only assertions referenced by the signed claim with a matching hash
+	// are reported; any mismatch invalidates the manifest
+	if store := manifest.child("c2pa.assertions"); store != nil {
+		assertions, err := c2paClaimedAssertions(claim, manifest, store, alg)
+		if err != nil {
+			md.Set(GroupC2PA, "Assertions", "invalid manifest, "+err.Error(), store.Offset, source)
+		} else {
+			md.Set(GroupC2PA, "Assertions", fmt.Sprintf("%d referenced by the claim, hashes match", len(assertions)), store.Offset, source)
+			reportC2PAAssertions(md, file, assertions, alg, source)
+		}
+	}
+
+	signature := manifest.child("c2pa.signature")
+	if signature == nil {
+		md.Set(GroupC2PA, "Signature", "missing", -1, source)
+		return nil
+	}
+	return reportC2PASignature(md, signature, claimBytes, source)
+}
+
+// c2paClaimGenerator returns claim_generator, or the name and version of
+// claim_generator_info (a map in v2 claims, an array in v1)
+func c2paClaimGenerator(claim interface{}) string {
+	if generator := cborString(cborMapValue(claim, "claim_generator")); generator != "" {
+		return generator
+	}
+	info := cborMapValue(claim, "claim_generator_info")
+	if list, ok := info.([]interface{}); ok && len(list) > 0 {
+		info = list[0]
+	}
+	name := cborString(cborMapValue(info, "name"))
+	if version := cborString(cborMapValue(info, "version")); name != "" && version != "" {
+		return name + " " + version
+	}
+	return name
+}
+
+// c2paClaimedAssertions returns the assertions listed in the claim's
+// hashed URIs ("assertions" in v1 claims, "created_assertions" and
+// "gathered_assertions" in v2), checking each hash over the assertion
+// superbox content. alg is the claim's default hash algorithm
+func c2paClaimedAssertions(claim interface{}, manifest, store *jumbfBox, alg string) ([]*jumbfBox, error) {
+	var refs []interface{}
+	for _, key := range []string{"assertions", "created_assertions", "gathered_assertions"} {
+		list, _ := cborMapValue(claim, key).([]interface{})
+		refs = append(refs, list...)
+	}
+
+	var assertions []*jumbfBox
+	for _, ref := range refs {
+		url := cborString(cborMapValue(ref, "url"))
+		label, err := c2paAssertionLabel(url, manifest.Label)
+		if err != nil {
+			return nil, err
+		}
+		assertion := store.child(label)
+		if assertion == nil {
+			return nil, fmt.Errorf("claimed assertion %s is missing", label)
+		}
+
+		hashAlg := cborString(cborMapValue(ref, "alg"))
+		if hashAlg == "" {
+			hashAlg = alg
+		}
+		h := newC2PAHash(hashAlg)
+		if h == nil {
+			return nil, fmt.Errorf("unsupported hash algorithm %s for %s", hashAlg, label)
+		}
+		expected, _ := cborMapValue(ref, "hash").([]byte)
+		h.Write(assertion.Payload)
+		if !bytes.Equal(h.Sum(nil), expected) {
+			return nil, fmt.Errorf("hash of %s does not match the claim", label)
+		}
+		assertions = append(assertions, assertion)
+	}
+	return assertions, nil
+}
+
+// c2paAssertionLabel resolves a JUMBF URI ("self#jumbf=c2pa.assertions/x"
+// or "self#jumbf=/c2pa/<manifest>/c2pa.assertions/x") to the label of an
+// assertion of the given manifest
+func c2paAssertionLabel(url, manifest string) (string, error) {
+	path := strings.TrimPrefix(url, "self#jumbf=")
+	if path == url {
+		return "", fmt.Errorf("unsupported assertion reference %q", url)
+	}
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if strings.HasPrefix(path, "/") {
+		if len(parts) != 4 || parts[0] != "c2pa" || parts[1] != manifest {
+			return "", fmt.Errorf("assertion reference %q is outside the manifest", url)
+		}
+		parts = parts[2:]
+	}
+	if len(parts) != 2 || parts[0] != "c2pa.assertions" || parts[1] == "" {
+		return "", fmt.Errorf("unsupported assertion reference %q", url)
+	}
+	return parts[1], nil
+}
+
+// reportC2PAAssertions reports the actions, ingredients and hard binding
+// of the verified assertions. alg is the claim's default hash algorithm
+func reportC2PAAssertions(md *Metadata, file []byte, assertions []*jumbfBox, alg string, source string) {
+	actions, ingredients := 0, 0
+	digitalSource := ""
+	binding := false
+	for _, assertion := range assertions {
+		// Repeated assertions are labelled "name__1", "name__2", ...
+		label := assertion.Label
+		if i := strings.Index(label, "__"); i > 0 {
+			label = label[:i]
+		}
+
+		switch {
+		case label == "c2pa.actions" || label == "c2pa.actions.v2":
+			value, _, err := assertion.cbor()
+			if err != nil {
+				md.Set(GroupErrors, "C2PA_ParseError", assertion.Label+": "+err.Error(), assertion.Offset, source)
+				continue
+			}
+			list, _ := cborMapValue(value, "actions").([]interface{})
+			for _, action := range list {
+				actions++
+				summary, dst := c2paAction(action)
+				md.Set(GroupC2PA, fmt.Sprintf("Action%d", actions), summary, assertion.Offset, source)
+				if digitalSource == "" {
+					digitalSource = dst
+				}
+			}
+
+		case strings.HasPrefix(label, "c2pa.ingredient"):
+			value, _, err := assertion.cbor()
+			if err != nil {
+				md.Set(GroupErrors, "C2PA_ParseError", assertion.Label+": "+err.Error(), assertion.Offset, source)
+				continue
+			}
+			ingredients++
+			md.Set(GroupC2PA, fmt.Sprintf("Ingredient%d", ingredients), c2paIngredient(value), assertion.Offset, source)
+
+		case label == "c2pa.hash.data":
+			value, _, err := assertion.cbor()
+			if err != nil {
+				md.Set(GroupErrors, "C2PA_ParseError", assertion.Label+": "+err.Error(), assertion.Offset, source)
+				continue
+			}
+			md.Set(GroupC2PA, "HardBinding", checkC2PADataHash(file, value, alg), assertion.Offset, source)
+			binding = true
+
+		case strings.HasPrefix(label, "c2pa.hash."):
+			// BMFF and box hashes cover the file structure, not a byte range
+			md.Set(GroupC2PA, "HardBinding", label+", not checked", assertion.Offset, source)
+			binding = true
+		}
+	}
+	if !binding {
+		md.Set(GroupC2PA, "HardBinding", "missing", -1, source)
+	}
+
+	if digitalSource != "" {
+		if description, ok := digitalSourceTypes[digitalSource]; ok {
+			digitalSource += " (" + description + ")"
+		}
+		md.Set(GroupC2PA, "DigitalSourceType", digitalSource, -1, source)
+	}
+}
+
+// c2paAction summarises an action as "c2pa.edited by Agent, sourceType"
+// and returns its digital source type
+func c2paAction(action interface{}) (string, string) {
+	summary := cborString(cborMapValue(action, "action"))
+
+	// softwareAgent is a string in v1 actions and a generator info map in v2
+	agent := cborMapValue(action, "softwareAgent")
+	name := cborString(agent)
+	if name == "" {
+		name = cborString(cborMapValue(agent, "name"))
+		if version := cborString(cborMapValue(agent, "version")); name != "" && version != "" {
+			name += " " + version
+		}
+	}
+	if name != "" {
+		summary += " by " + name
+	}
+
+	// Digital source types are IPTC URIs; the last path segment names them
+	dst := cborString(cborMapValue(action, "digitalSourceType"))
+	if i := strings.LastIndex(dst, "/"); i >= 0 {
+		dst = dst[i+1:]
+	}
+	if dst != "" {
+		summary += ", " + dst
+	}
+	return summary, dst
+}
+
+// c2paIngredient summarises an ingredient as "title (relationship), format"
+func c2paIngredient(ingredient interface{}) string {
+	title := cborString(cborMapValue(ingredient, "dc:title"))
+	if title == "" {
+		title = cborString(cborMapValue(ingredient, "title"))
+	}
+	if title == "" {
+		title = "untitled"
+	}
+	relationship := cborString(cborMapValue(ingredient, "relationship"))
+	if relationship == "" {
+		relationship = "componentOf"
+	}
+	summary := title + " (" + relationship + ")"
+	if format := cborString(cborMapValue(ingredient, "dc:format")); format != "" {
+		summary += ", " + format
+	}
+	return summary
+}
+
+// checkC2PADataHash hashes the file without the excluded byte ranges
+// (the manifest store itself) and compares it with a c2pa.hash.data
+// assertion. It is skipped when file is nil
+func checkC2PADataHash(file []byte, binding interface{}, defaultAlg string) string {
+	alg := cborString(cborMapValue(binding, "alg"))
+	if alg == "" {
+		alg = defaultAlg
+	}
+	expected, ok := cborMapValue(binding, "hash").([]byte)
+	if !ok {
+		return "c2pa.hash.data, missing hash"
+	}
+	if file == nil {
+		return fmt.Sprintf("c2pa.hash.data (%s), not checked (embedded image)", alg)
+	}
+	h := newC2PAHash(alg)
+	if h == nil {
+		return "c2pa.hash.data, unsupported algorithm " + alg
+	}
+
+	type exclusion struct{ start, length int64 }
+	var exclusions []exclusion
+	list, _ := cborMapValue(binding, "exclusions").([]interface{})
+	for _, item := range list {
+		start, _ := cborMapValue(item, "start").(int64)
+		length, _ := cborMapValue(item, "length").(int64)
+		exclusions = append(exclusions, exclusion{start, length})
+	}
+	sort.Slice(exclusions, func(i, j int) bool { return exclusions[i].start < exclusions[j].start })
+
+	pos := int64(0)
+	size := int64(len(file))
+	for _, e := range exclusions {
+		if e.start < pos || e.length < 0 || e.length > size-e.start {
+			return fmt.Sprintf("c2pa.hash.data (%s), invalid exclusion range %d+%d", alg, e.start, e.length)
+		}
+		h.Write(file[pos:e.start])
+		pos = e.start + e.length
+	}
+	h.Write(file[pos:])
+
+	if bytes.Equal(h.Sum(nil), expected) {
+		return fmt.Sprintf("c2pa.hash.data (%s), matches", alg)
+	}
+	return fmt.Sprintf("c2pa.hash.data (%s), does not match (file modified)", alg)
+}
+
+// newC2PAHash returns the hash named by a C2PA alg value, or nil
+func newC2PAHash(alg string) hash.Hash {
+	switch alg {
+	case "sha256":
+		return sha256.New()
+	case "sha384":
+		return sha512.New384()
+	case "sha512":
+		return sha512.New()
+	}
+	return nil
+}
+
+// reportC2PASignature reports the signer of the claim and verifies the
+// COSE_Sign1 signature and, with a trust list, its certificate chain
+func reportC2PASignature(md *Metadata, box *jumbfBox, claim []byte, source string) error {
+	var payload []byte
+	for _, c := range box.Content {
+		if c.Type == "cbor" {
+			payload = c.Payload
+		}
+	}
+	tag, value, err := decodeCBORTagged(payload)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+	sign1, ok := value.([]interface{})
+	if (tag != coseSign1Tag && tag != -1) || !ok || len(sign1) != 4 {
+		return fmt.Errorf("signature is not a COSE_Sign1 structure")
+	}
+	protected, _ := sign1[0].([]byte)
+	signature, _ := sign1[3].([]byte)
+	headers, err := decodeCBOR(protected)
+	if err != nil {
+		return fmt.Errorf("invalid COSE protected header: %w", err)
+	}
+
+	// The algorithm is protected; the chain may be in either header
+	alg := "unspecified"
+	supported := false
+	if id, ok := coseHeader(headers, coseHeaderAlg).(int64); ok {
+		alg = lookupCode(coseAlgorithms, int(id))
+		_, supported = coseAlgorithms[int(id)]
+	}
+	x5chain := coseHeader(headers, coseHeaderX5Chain)
+	if x5chain == nil {
+		x5chain = coseHeader(sign1[1], coseHeaderX5Chain)
+	}
+	// A single certificate is not wrapped in an array
+	if der, ok := x5chain.([]byte); ok {
+		x5chain = []interface{}{der}
+	}
+	var chain []*derCertificate
+	list, _ := x5chain.([]interface{})
+	for i, item := range list {
+		der, _ := item.([]byte)
+		cert, err := parseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("invalid certificate %d in x5chain: %w", i+1, err)
+		}
+		chain = append(chain, cert)
+	}
+
+	md.Set(GroupC2PA, "SignatureAlgorithm", alg, box.Offset, source)
+	if len(chain) == 0 {
+		md.Set(GroupC2PA, "Signature", "no signing certificate", box.Offset, source)
+		return nil
+	}
+	leaf := chain[0]
+	issuer := derNameAttribute(leaf.Subject, oidOrganization)
+	if issuer == "" {
+		issuer = derNameAttribute(leaf.Subject, oidCommonName)
+	}
+	md.Set(GroupC2PA, "SignatureIssuer", issuer, box.Offset, source)
+	md.Set(GroupC2PA, "CertificateIssuer", derDisplayName(leaf.Issuer), box.Offset, source)
+
+	// Sig_structure = ["Signature1", protected, external_aad, payload]
+	// with the claim as the detached payload
+	var toBeSigned []byte
+	toBeSigned = append(toBeSigned, cborHead(4, 4)...)
+	toBeSigned = append(toBeSigned, cborHead(3, len("Signature1"))...)
+	toBeSigned = append(toBeSigned, "Signature1"...)
+	toBeSigned = append(toBeSigned, cborHead(2, len(protected))...)
+	toBeSigned = append(toBeSigned, protected...)
+	toBeSigned = append(toBeSigned, cborHead(2, 0)...)
+	toBeSigned = append(toBeSigned, cborHead(2, len(claim))...)
+	toBeSigned = append(toBeSigned, claim...)
+
+	if !supported {
+		md.Set(GroupC2PA, "Signature", "not checked (unsupported algorithm)", box.Offset, source)
+	} else if err := leaf.verify(alg, toBeSigned, signature); err != nil {
+		md.Set(GroupC2PA, "Signature", "invalid, "+err.Error(), box.Offset, source)
+	} else {
+		md.Set(GroupC2PA, "Signature", "valid", box.Offset, source)
+	}
+	md.Set(GroupC2PA, "CertificateChain", checkC2PAChain(chain), box.Offset, source)
+	return nil
+}
+
+// coseHeader looks up an integer label in a decoded COSE header map
+func coseHeader(headers interface{}, label int64) interface{} {
+	if m, ok := headers.(map[interface{}]interface{}); ok {
+		return m[label]
+	}
+	return nil
+}
+
+// c2paSignerUsages are the extKeyUsage purposes allowed for C2PA claim
+// signing certificates: emailProtection, documentSigning and
+// c2pa-kp-claimSigning
+var c2paSignerUsages = []string{
+	"1.3.6.1.5.5.7.3.4",
+	"1.3.6.1.5.5.7.3.36",
+	"1.3.6.1.4.1.62558.2.1",
+}
+
+// oidAnyExtKeyUsage is forbidden in signing certificates
+const oidAnyExtKeyUsage = "2.5.29.37.0"
+
+// checkC2PAChain verifies each certificate of the chain against the next
+// and the chain against the trust list. The signer must be an end-entity
+// certificate allowed to sign claims, every issuer a CA allowed to sign
+// certificates, and every certificate valid now
+func checkC2PAChain(chain []*derCertificate) string {
+	now := time.Now()
+	for i, cert := range chain {
+		if !cert.validAt(now) {
+			return fmt.Sprintf("invalid, certificate %d is not valid now (%s to %s)", i+1,
+				cert.NotBefore.Format("2006:01:02"), cert.NotAfter.Format("2006:01:02"))
+		}
+	}
+	if err := checkC2PASigner(chain[0]); err != nil {
+		return "invalid, signing certificate " + err.Error()
+	}
+	for i := 0; i+1 < len(chain); i++ {
+		if err := checkC2PAIssuer(chain[i+1]); err != nil {
+			return fmt.Sprintf("invalid, certificate %d %v", i+2, err)
+		}
+		if err := chain[i].checkSignedBy(chain[i+1]); err != nil {
+			return fmt.Sprintf("invalid, certificate %d: %v", i+1, err)
+		}
+	}
+	if len(c2paTrustAnchors) == 0 {
+		return fmt.Sprintf("%d certificates, not checked against a trust list", len(chain))
+	}
+
+	// The chain is trusted once one of its certificates is an anchor or
+	// is signed by a valid CA anchor
+	for _, cert := range chain {
+		for _, anchor := range c2paTrustAnchors {
+			if bytes.Equal(cert.Raw, anchor.Raw) {
+				return "trusted, " + derDisplayName(anchor.Subject)
+			}
+			if checkC2PAIssuer(anchor) == nil && anchor.validAt(now) && cert.checkSignedBy(anchor) == nil {
+				return "trusted, " + derDisplayName(anchor.Subject)
+			}
+		}
+	}
+	return "not trusted, issuer " + derDisplayName(chain[len(chain)-1].Issuer) + " is not in the trust list"
+}
+
+// checkC2PASigner applies the C2PA profile to the claim signing certificate
+func checkC2PASigner(cert *derCertificate) error {
+	if cert.IsCA {
+		return fmt.Errorf("is a CA certificate")
+	}
+	if !cert.hasKeyUsage(keyUsageDigitalSignature) {
+		return fmt.Errorf("does not allow digitalSignature")
+	}
+	allowed := false
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == oidAnyExtKeyUsage {
+			return fmt.Errorf("allows any extended key usage")
+		}
+		for _, signer := range c2paSignerUsages {
+			if usage == signer {
+				allowed = true
+			}
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("has no C2PA signing extended key usage")
+	}
+	return nil
+}
+
+// checkC2PAIssuer checks that a certificate may issue certificates
+func checkC2PAIssuer(cert *derCertificate) error {
+	if !cert.IsCA {
+		return fmt.Errorf("is not a CA certificate")
+	}
+	if !cert.hasKeyUsage(keyUsageCertSign) {
+		return fmt.Errorf("does not allow keyCertSign")
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+package parser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Canon MakerNote tags
+// The MakerNote is a plain IFD whose offsets are relative to the TIFF
+// header, so it is read with the same tiffReader as the EXIF IFDs
+const (
+	canonCameraSettings = 0x0001
+	canonShotInfo       = 0x0004
+	canonSerialNumber   = 0x000C
+	canonAFInfo         = 0x0012
+	canonAFInfo2        = 0x0026
+	canonFileInfo       = 0x0093
+)
+
+var canonTags = tagTable{
+	0x0006: {Name: "ImageType", Type: typeASCII},
+	0x0007: {Name: "FirmwareVersion", Type: typeASCII},
+	0x0008: {Name: "FileNumber", Type: typeLong, Count: 1, Format: formatCanonFileNumber},
+	0x0009: {Name: "OwnerName", Type: typeASCII},
+	0x0010: {Name: "ModelID", Type: typeLong, Count: 1, Format: formatHex32},
+	0x0095: {Name: "LensModel", Type: typeASCII},
+	0x0096: {Name: "InternalSerialNumber", Type: typeASCII},
+	0x00B4: {Name: "ColorSpace", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{1: "sRGB", 2: "Adobe RGB"})},
+}
+
+// canonCameraSettingsFields lists the CameraSettings (0x0001) fields
+// Focal lengths need FocalUnits and are reported separately
+var canonCameraSettingsFields = []arrayField{
+	{1, "MacroMode", formatEnum(map[int]string{1: "Macro", 2: "Normal"})},
+	{2, "SelfTimer", formatCanonSelfTimer},
+	{3, "Quality", formatEnum(map[int]string{
+		1: "Economy", 2: "Normal", 3: "Fine", 4: "RAW", 5: "Superfine", 7: "CRAW",
+		130: "Light (RAW)", 131: "Standard (RAW)",
+	})},
+	{4, "CanonFlashMode", formatEnum(map[int]string{
+		0: "Off", 1: "Auto", 2: "On", 3: "Red-eye reduction", 4: "Slow-sync",
+		5: "Red-eye reduction (Auto)", 6: "Red-eye reduction (On)", 16: "External flash",
+	})},
+	{5, "ContinuousDrive", formatEnum(map[int]string{
+		0: "Single", 1: "Continuous", 2: "Movie", 3: "Continuous, Speed Priority",
+		4: "Continuous, Low", 5: "Continuous, High", 6: "Silent Single",
+		9: "Single, Silent", 10: "Continuous, Silent",
+	})},
+	{7, "FocusMode", formatEnum(map[int]string{
+		0: "One-shot AF", 1: "AI Servo AF", 2: "AI Focus AF", 3: "Manual Focus (3)",
+		4: "Single", 5: "Continuous", 6: "Manual Focus (6)", 16: "Pan Focus",
+		256: "One-shot AF (Live View)", 257: "AI Servo AF (Live View)",
+		258: "AI Focus AF (Live View)", 512: "Movie Snap Focus", 519: "Movie Servo AF",
+	})},
+	{9, "RecordMode", formatEnum(map[int]string{
+		1: "JPEG", 2: "CRW+THM", 3: "AVI+THM", 4: "TIF", 5: "TIF+JPEG", 6: "CR2",
+		7: "CR2+JPEG", 9: "MOV", 10: "MP4", 11: "CRM", 12: "CR3", 13: "CR3+JPEG",
+		14: "HIF", 15: "CR3+HIF",
+	})},
+	{17, "MeteringMode", formatEnum(map[int]string{
+		0: "Default", 1: "Spot", 2: "Average", 3: "Evaluative", 4: "Partial",
+		5: "Center-weighted average",
+	})},
+	{18, "FocusRange", formatEnum(map[int]string{
+		0: "Manual", 1: "Auto", 2: "Not Known", 3: "Macro", 4: "Very Close", 5: "Close",
+		6: "Middle Range", 7: "Far Range", 8: "Pan Focus", 9: "Super Macro", 10: "Infinity",
+	})},
+	{20, "CanonExposureMode", formatEnum(map[int]string{
+		0: "Easy", 1: "Program AE", 2: "Shutter speed priority AE",
+		3: "Aperture-priority AE", 4: "Manual", 5: "Depth-of-field AE", 6: "M-Dep",
+		7: "Bulb", 8: "Flexible-priority AE",
+	})},
+	{22, "LensType", nil},
+	{26, "MaxAperture", formatCanonAperture},
+	{27, "MinAperture", formatCanonAperture},
+	{32, "FocusContinuous", formatEnum(map[int]string{0: "Single", 1: "Continuous", 8: "Manual"})},
+	{34, "ImageStabilization", formatEnum(map[int]string{
+		0: "Off", 1: "On", 2: "Shoot Only", 3: "Panning", 4: "Dynamic",
+		256: "Off (2)", 257: "On (2)", 258: "Shoot Only (2)", 259: "Panning (2)", 260: "Dynamic (2)",
+	})},
+}
+
+// canonShotInfoFields lists the ShotInfo (0x0004) fields
+var canonShotInfoFields = []arrayField{
+	{1, "AutoISO", func(v interface{}) string {
+		n, _ := firstInt(v)
+		return formatDecimal(math.Exp2(float64(n)/32)*100, 0) + "%"
+	}},
+	{2, "BaseISO", func(v interface{}) string {
+		n, _ := firstInt(v)
+		return formatDecimal(math.Exp2(float64(n)/32)*100/32, 0)
+	}},
+	{4, "TargetAperture", formatCanonAperture},
+	{5, "TargetExposureTime", formatCanonExposureTime},
+	{6, "ExposureCompensation", formatCanonEV},
+	{7, "WhiteBalance", formatEnum(map[int]string{
+		0: "Auto", 1: "Daylight", 2: "Cloudy", 3: "Tungsten", 4: "Fluorescent", 5: "Flash",
+		6: "Custom", 7: "Black & White", 8: "Shade", 9: "Manual Temperature (Kelvin)",
+		14: "Daylight Fluorescent", 15: "Custom 1", 16: "Custom 2", 17: "Underwater",
+		18: "Custom 3", 19: "Custom 4", 23: "Auto (ambience priority)",
+	})},
+	{9, "SequenceNumber", nil},
+	{15, "FlashExposureComp", formatCanonEV},
+	{19, "FocusDistanceUpper", formatCanonFocusDistance},
+	{20, "FocusDistanceLower", formatCanonFocusDistance},
+	{26, "CameraType", formatEnum(map[int]string{
+		248: "EOS High-end", 250: "Compact", 252: "EOS Mid-range", 255: "DV Camera",
+	})},
+	{27, "AutoRotate", formatEnum(map[int]string{
+		0: "None", 1: "Rotate 90 CW", 2: "Rotate 180", 3: "Rotate 270 CW",
+	})},
+}
+
+// canonFileInfoFields lists the FileInfo (0x0093) fields
+var canonFileInfoFields = []arrayField{
+	{3, "BracketMode", formatEnum(map[int]string{0: "Off", 1: "AEB", 2: "FEB", 3: "ISO", 4: "WB"})},
+	{4, "BracketValue", formatCanonEV},
+	{5, "BracketShotNumber", nil},
+	{19, "LiveViewShooting", formatEnum(map[int]string{0: "Off", 1: "On"})},
+}
+
+// canonAFAreaModeNames are the AFInfo2 AFAreaMode values
+var canonAFAreaModeNames = map[int]string{
+	0: "Off (Manual Focus)", 1: "AF Point Expansion (surround)", 2: "Single-point AF",
+	4: "Auto", 5: "Face Detect AF", 6: "Face + Tracking", 7: "Zone AF",
+	8: "AF Point Expansion (4 point)", 9: "Spot AF", 10: "AF Point Expansion (8 point)",
+	11: "Flexizone Multi (49 point)", 12: "Flexizone Multi (9 point)",
+	13: "Flexizone Single", 14: "Large Zone AF",
+}
+
+// parseCanonMakerNote decodes a Canon MakerNote IFD
+func parseCanonMakerNote(p *SimpleExifParser, r *tiffReader, offset, size int) {
+	model := makerNoteModel(r)
+
+	p.parseMakerNoteIFD(r, offset, GroupCanon, canonTags, func(tag uint16, raw interface{}, entryOffset int) bool {
+		switch tag {
+		case canonCameraSettings:
+			values := makerNoteInts(raw, true)
+			r.setArrayFields(GroupCanon, tag, values, canonCameraSettingsFields, entryOffset, canonNotApplicable)
+			reportCanonFocalRange(r, values, entryOffset)
+		case canonShotInfo:
+			r.setArrayFields(GroupCanon, tag, makerNoteInts(raw, true), canonShotInfoFields, entryOffset, canonNotApplicable)
+		case canonFileInfo:
+			values := makerNoteInts(raw, true)
+			r.setArrayFields(GroupCanon, tag, values, canonFileInfoFields, entryOffset, canonNotApplicable)
+			reportCanonShutterCount(r, model, values, entryOffset)
+		case canonAFInfo:
+			reportCanonAFInfo(r, makerNoteInts(raw, false), false, entryOffset)
+		case canonAFInfo2:
+			reportCanonAFInfo(r, makerNoteInts(raw, false), true, entryOffset)
+		case canonSerialNumber:
+			serial, ok := firstInt(raw)
+			if !ok {
+				return true
+			}
+			// EOS-1D bodies use 6 digits, everything else 10
+			format := "%010d"
+			if strings.Contains(model, "EOS-1D") {
+				format = "%06d"
+			}
+			r.set(GroupCanon, tag, "SerialNumber", raw, fmt.Sprintf(format, serial), entryOffset)
+		default:
+			return false
+		}
+		return true
+	})
+}
+
+// canonNotApplicable reports the -1 that Canon stores in unused fields
+func canonNotApplicable(v int) bool {
+	return v == -1
+}
+
+// reportCanonFocalRange reports the lens focal range from CameraSettings
+// (MaxFocalLength and MinFocalLength are in FocalUnits per mm)
+func reportCanonFocalRange(r *tiffReader, values []int, entryOffset int) {
+	if len(values) <= 25 {
+		return
+	}
+	longFocal, shortFocal, units := values[23], values[24], values[25]
+	if units <= 0 {
+		units = 1
+	}
+	if longFocal <= 0 || shortFocal <= 0 {
+		return
+	}
+
+	short := float64(shortFocal) / float64(units)
+	long := float64(longFocal) / float64(units)
+	r.set(GroupCanon, canonCameraSettings, "MinFocalLength", []int32{int32(shortFocal)}, formatDecimal(short, 1)+" mm", entryOffset)
+	r.set(GroupCanon, canonCameraSettings, "MaxFocalLength", []int32{int32(longFocal)}, formatDecimal(long, 1)+" mm", entryOffset)
+
+	lens := formatDecimal(short, 1) + " mm"
+	if long != short {
+		lens = formatDecimal(short, 1) + "-" + formatDecimal(long, 1) + " mm"
+	}
+	r.md.Set(GroupCanon, "Lens", lens, r.base+entryOffset, r.source)
+}
+
+// reportCanonShutterCount reports the shutter count stored in FileInfo
+// Only the EOS 30D/400D generation writes it here: the first two fields
+// hold a 32-bit value with its 16-bit halves swapped
+func reportCanonShutterCount(r *tiffReader, model string, values []int, entryOffset int) {
+	if len(values) < 3 {
+		return
+	}
+	bodies := []string{"30D", "400D", "REBEL XTi", "Kiss Digital X"}
+	supported := false
+	for _, body := range bodies {
+		if strings.Contains(model, body) {
+			supported = true
+		}
+	}
+	if !supported {
+		return
+	}
+
+	// values[1] and values[2] are the two halves in file order
+	first, second := uint32(uint16(values[1])), uint32(uint16(values[2]))
+	count := first<<16 | second
+	if r.byteOrder == binary.BigEndian {
+		count = second<<16 | first
+	}
+	r.set(GroupCanon, canonFileInfo, "ShutterCount", []uint32{count}, fmt.Sprintf("%d", count), entryOffset)
+}
+
+// reportCanonAFInfo decodes AFInfo (0x0012) and AFInfo2 (0x0026)
+// AFInfo2 starts with its size and the AF area mode; both then list the
+// number of AF points, the image sizes, the area sizes and positions and
+// finally bit masks of the points in focus
+func reportCanonAFInfo(r *tiffReader, values []int, v2 bool, entryOffset int) {
+	tag := uint16(canonAFInfo)
+	start := 0
+	if v2 {
+		tag = canonAFInfo2
+		if len(values) < 2 {
+			return
+		}
+		r.setArrayFields(GroupCanon, tag, values, []arrayField{{1, "AFAreaMode", formatEnum(canonAFAreaModeNames)}}, entryOffset, nil)
+		start = 2
+	}
+	if len(values) < start+6 {
+		return
+	}
+
+	points := values[start]
+	r.setArrayFields(GroupCanon, tag, values[start:], []arrayField{
+		{0, "NumAFPoints", nil},
+		{1, "ValidAFPoints", nil},
+	}, entryOffset, nil)
+	r.md.Set(GroupCanon, "AFImageSize", fmt.Sprintf("%dx%d", values[start+4], values[start+5]), r.base+entryOffset, r.source)
+
+	// AFInfo has one width/height for all points, AFInfo2 one per point
+	masks := start + 6 + 2 + 2*points
+	if v2 {
+		masks = start + 6 + 4*points
+	}
+	words := (points + 15) / 16
+	if points <= 0 || masks+words > len(values) {
+		return
+	}
+
+	var inFocus []string
+	for i := 0; i < points; i++ {
+		if values[masks+i/16]&(1<<uint(i%16)) != 0 {
+			inFocus = append(inFocus, fmt.Sprintf("%d", i))
+		}
+	}
+	value := "(none)"
+	if len(inFocus) > 0 {
+		value = strings.Join(inFocus, ",")
+	}
+	r.md.Set(GroupCanon, "AFPointsInFocus", value, r.base+entryOffset, r.source)
+}
+
+// canonEV converts a Canon EV-coded value, where the fractional codes
+// 0x0C and 0x14 stand for 1/3 and 2/3 steps
+func canonEV(v int) float64 {
+	sign := 1.0
+	if v < 0 {
+		sign = -1
+		v = -v
+	}
+	frac := float64(v & 0x1F)
+	v -= v & 0x1F
+	switch frac {
+	case 0x0C:
+		frac = 32.0 / 3
+	case 0x14:
+		frac = 64.0 / 3
+	}
+	return sign * (float64(v) + frac) / 32
+}
+
+func formatCanonEV(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok {
+		return ""
+	}
+	ev := canonEV(v)
+	if ev > 0 {
+		return "+" + formatDecimal(ev, 1) + " EV"
+	}
+	return formatDecimal(ev, 1) + " EV"
+}
+
+func formatCanonAperture(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok || v == 0 {
+		return ""
+	}
+	return "f/" + formatDecimal(math.Exp2(canonEV(v)/2), 1)
+}
+
+func formatCanonExposureTime(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok || v == 0 {
+		return ""
+	}
+	return formatSeconds(math.Exp2(-canonEV(v)))
+}
+
+func formatCanonFocusDistance(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok || v == 0 {
+		return ""
+	}
+	return formatDecimal(float64(uint16(v))/100, 2) + " m"
+}
+
+func formatCanonSelfTimer(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok {
+		return ""
+	}
+	if v == 0 {
+		return "Off"
+	}
+	text := formatDecimal(float64(v&0xFFF)/10, 1) + " s"
+	if v&0x4000 != 0 {
+		text += ", Custom"
+	}
+	return text
+}
+
+// formatCanonFileNumber splits the folder number from the file number
+// (1001234 -> "100-1234")
+func formatCanonFileNumber(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok {
+		return ""
+	}
+	text := fmt.Sprintf("%d", v)
+	if len(text) > 4 {
+		return text[:len(text)-4] + "-" + text[len(text)-4:]
+	}
+	return text
+}
+
+func formatHex32(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("0x%08X", uint32(v))
+}
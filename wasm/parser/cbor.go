@@ -0,0 +1,278 @@
+package parser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// A minimal CBOR (RFC 8949) decoder for C2PA claims, assertions and COSE
+// signatures. Values decode to:
+//   unsigned and negative integers  int64 (uint64 above math.MaxInt64)
+//   byte strings                     []byte
+//   text strings                     string
+//   arrays                           []interface{}
+//   maps                             map[interface{}]interface{}
+//   floats                           float64
+//   true/false, null/undefined       bool, nil
+// Tags are dropped and their content returned, except that the tag
+// number of the outermost item is available from decodeCBORTagged.
+
+// maxCBORDepth bounds the nesting of arrays, maps and tags
+const maxCBORDepth = 32
+
+// cborBreak marks the end of an indefinite-length item
+type cborBreak struct{}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+// decodeCBOR decodes the single item in data
+func decodeCBOR(data []byte) (interface{}, error) {
+	_, v, err := decodeCBORTagged(data)
+	return v, err
+}
+
+// decodeCBORTagged decodes the single item in data and returns the tag
+// number of its outermost tag (-1 if untagged)
+func decodeCBORTagged(data []byte) (int64, interface{}, error) {
+	d := &cborDecoder{data: data}
+	tag := int64(-1)
+	if len(data) > 0 && data[0]>>5 == 6 {
+		_, arg, err := d.head()
+		if err != nil {
+			return 0, nil, err
+		}
+		tag = int64(arg)
+	}
+	v, err := d.value(0)
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, ok := v.(cborBreak); ok {
+		return 0, nil, fmt.Errorf("unexpected CBOR break")
+	}
+	return tag, v, nil
+}
+
+// head reads an initial byte and its argument
+// For indefinite lengths the argument is math.MaxUint64
+func (d *cborDecoder) head() (byte, uint64, error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, fmt.Errorf("CBOR truncated at offset %d", d.pos)
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, info := b>>5, b&0x1F
+
+	var size int
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	case info == 31:
+		return major, math.MaxUint64, nil
+	default:
+		return 0, 0, fmt.Errorf("invalid CBOR additional info %d", info)
+	}
+	if d.pos+size > len(d.data) {
+		return 0, 0, fmt.Errorf("CBOR truncated at offset %d", d.pos)
+	}
+	var arg uint64
+	for _, x := range d.data[d.pos : d.pos+size] {
+		arg = arg<<8 | uint64(x)
+	}
+	d.pos += size
+	return major, arg, nil
+}
+
+// bytes reads n bytes of string content
+func (d *cborDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("CBOR string truncated at offset %d", d.pos)
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+func (d *cborDecoder) value(depth int) (interface{}, error) {
+	if depth > maxCBORDepth {
+		return nil, fmt.Errorf("CBOR nesting too deep")
+	}
+	start := d.pos
+	major, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	indefinite := arg == math.MaxUint64 && d.data[start]&0x1F == 31
+
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+		return int64(arg), nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("CBOR negative integer out of range")
+		}
+		return -1 - int64(arg), nil
+
+	case 2, 3:
+		var b []byte
+		if indefinite {
+			// Concatenated definite-length chunks of the same type
+			for {
+				chunk, err := d.value(depth + 1)
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := chunk.(cborBreak); ok {
+					break
+				}
+				switch c := chunk.(type) {
+				case []byte:
+					b = append(b, c...)
+				case string:
+					b = append(b, c...)
+				default:
+					return nil, fmt.Errorf("invalid CBOR string chunk")
+				}
+			}
+		} else if b, err = d.bytes(arg); err != nil {
+			return nil, err
+		}
+		if major == 3 {
+			return string(b), nil
+		}
+		return b, nil
+
+	case 4:
+		var items []interface{}
+		for i := uint64(0); indefinite || i < arg; i++ {
+			item, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := item.(cborBreak); ok {
+				if !indefinite {
+					return nil, fmt.Errorf("unexpected CBOR break")
+				}
+				break
+			}
+			items = append(items, item)
+		}
+		return items, nil
+
+	case 5:
+		m := make(map[interface{}]interface{})
+		for i := uint64(0); indefinite || i < arg; i++ {
+			key, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := key.(cborBreak); ok {
+				if !indefinite {
+					return nil, fmt.Errorf("unexpected CBOR break")
+				}
+				break
+			}
+			value, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, uint64, string, bool:
+				m[key] = value
+			default:
+				// Other keys are not used by C2PA
+			}
+		}
+		return m, nil
+
+	case 6:
+		return d.value(depth + 1)
+
+	default: // 7: simple values and floats
+		info := d.data[start] & 0x1F
+		switch {
+		case info == 20:
+			return false, nil
+		case info == 21:
+			return true, nil
+		case info == 22, info == 23:
+			return nil, nil
+		case info == 25:
+			return halfFloat(uint16(arg)), nil
+		case info == 26:
+			return float64(math.Float32frombits(uint32(arg))), nil
+		case info == 27:
+			return math.Float64frombits(arg), nil
+		case info == 31:
+			return cborBreak{}, nil
+		}
+		return int64(arg), nil
+	}
+}
+
+// halfFloat converts an IEEE 754 half-precision value
+func halfFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1F
+	mant := float64(h & 0x3FF)
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -v
+	}
+	return v
+}
+
+// cborHead encodes the initial byte and argument of a CBOR item
+func cborHead(major byte, n int) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xFF:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xFFFF:
+		return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+	}
+	b := []byte{major<<5 | 26, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], uint32(n))
+	return b
+}
+
+// cborMapValue looks up a text key in a decoded map
+func cborMapValue(v interface{}, key string) interface{} {
+	if m, ok := v.(map[interface{}]interface{}); ok {
+		return m[key]
+	}
+	return nil
+}
+
+// cborString returns a text string value, or ""
+func cborString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
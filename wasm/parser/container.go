@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Google's Container XMP (used by Ultra HDR, Motion Photo and depth
+// photos) lists the media items stored in a JPEG: the primary image first,
+// then each secondary item concatenated after the primary image's EOI in
+// directory order, separated by the padding declared for each item
+
+// EmbeddedMedia is a media item stored inside the image file, such as a
+// Motion Photo video or a depth map
+type EmbeddedMedia struct {
+	// Semantic is the Container:Directory role ("MotionPhoto", "Depth",
+	// "GainMap", ...)
+	Semantic string
+
+	MIMEType string
+
+	// Offset is the absolute file offset of the item, or -1 when it was
+	// decoded from XMP
+	Offset int
+
+	Data []byte
+}
+
+// ErrNoEmbeddedMedia is returned when the image has no item with the
+// requested semantic
+var ErrNoEmbeddedMedia = errors.New("no embedded media found")
+
+// EmbeddedMedia returns the media items found while parsing
+func (m *Metadata) EmbeddedMedia() []EmbeddedMedia {
+	return m.media
+}
+
+// ExtractEmbeddedMedia parses the image and returns its first media item
+// with the given semantic
+func ExtractEmbeddedMedia(data []byte, semantic string) (*EmbeddedMedia, error) {
+	md, err := ParseImage(data)
+	if err != nil {
+		return nil, err
+	}
+	media := findMedia(md, semantic)
+	if media == nil {
+		return nil, ErrNoEmbeddedMedia
+	}
+	return media, nil
+}
+
+// containerItem is one entry of Container:Directory
+type containerItem struct {
+	Mime     string
+	Semantic string
+	Length   int
+	Padding  int
+
+	// Offset is the absolute file offset of the item (-1 if unknown)
+	Offset int
+}
+
+// readContainerDirectory returns the items of Container:Directory in the
+// XMP group, or nil without a directory
+func readContainerDirectory(md *Metadata) []containerItem {
+	var items []containerItem
+	for i := 1; ; i++ {
+		prefix := fmt.Sprintf("Container:Directory[%d]/Container:Item/", i)
+		value := func(name string) string {
+			if e := md.Get(GroupXMP, prefix+"Item:"+name); e != nil {
+				return e.Value
+			}
+			return ""
+		}
+		number := func(name string) int {
+			n, err := strconv.Atoi(value(name))
+			if err != nil || n < 0 {
+				return 0
+			}
+			return n
+		}
+
+		mime, semantic := value("Mime"), value("Semantic")
+		if mime == "" && semantic == "" {
+			return items
+		}
+		items = append(items, containerItem{
+			Mime:     mime,
+			Semantic: semantic,
+			Length:   number("Length"),
+			Padding:  number("Padding"),
+			Offset:   -1,
+		})
+	}
+}
+
+// locateContainerItems sets the offset of each secondary item, given the
+// end of the primary image. Items that do not fit in the file keep -1.
+// Lengths and paddings come from XMP, so each is checked against the
+// bytes left before it is added
+func locateContainerItems(items []containerItem, primaryEnd, fileSize int) {
+	if len(items) == 0 || primaryEnd < 0 || primaryEnd > fileSize {
+		return
+	}
+	items[0].Offset = 0
+	if items[0].Padding > fileSize-primaryEnd {
+		return
+	}
+	pos := primaryEnd + items[0].Padding
+	for i := 1; i < len(items); i++ {
+		if items[i].Length > fileSize-pos {
+			// Later items start after this one and cannot fit either
+			return
+		}
+		if items[i].Length > 0 {
+			items[i].Offset = pos
+		}
+		pos += items[i].Length
+		if items[i].Padding > fileSize-pos {
+			return
+		}
+		pos += items[i].Padding
+	}
+}
+
+// findContainerItem returns the first located item with the given semantic
+func findContainerItem(items []containerItem, semantic string) *containerItem {
+	for i := range items {
+		if items[i].Semantic == semantic && items[i].Offset > 0 {
+			return &items[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// containerJPEG builds a JPEG whose XMP Container:Directory declares a
+// primary image and one secondary item with the given length
+func containerJPEG(length string) []byte {
+	xmp := `http://ns.adobe.com/xap/1.0/` + "\x00" +
+		`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+		`<rdf:Description xmlns:Container="http://ns.google.com/photos/1.0/container/" ` +
+		`xmlns:Item="http://ns.google.com/photos/1.0/container/item/" ` +
+		`xmlns:GCamera="http://ns.google.com/photos/1.0/camera/" GCamera:MotionPhoto="1">` +
+		`<Container:Directory><rdf:Seq>` +
+		`<rdf:li rdf:parseType="Resource"><Container:Item Item:Mime="image/jpeg" Item:Semantic="Primary"/></rdf:li>` +
+		`<rdf:li rdf:parseType="Resource"><Container:Item Item:Mime="video/mp4" Item:Semantic="MotionPhoto" Item:Length="` + length + `"/></rdf:li>` +
+		`<rdf:li rdf:parseType="Resource"><Container:Item Item:Mime="image/jpeg" Item:Semantic="GainMap" Item:Length="` + length + `"/></rdf:li>` +
+		`</rdf:Seq></Container:Directory></rdf:Description></rdf:RDF></x:xmpmeta>`
+
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(data[4:], uint16(len(xmp)+2))
+	data = append(data, xmp...)
+	data = append(data, 0xFF, 0xD9)
+	return append(data, make([]byte, 16)...)
+}
+
+func TestContainerItemLengthOverflow(t *testing.T) {
+	for _, length := range []string{"9223372036854775800", "2147483647", "17"} {
+		md, err := ParseImage(containerJPEG(length))
+		if err != nil {
+			t.Fatalf("Length %s: %v", length, err)
+		}
+		if media := md.EmbeddedMedia(); len(media) != 0 {
+			t.Errorf("Length %s: located %d items past the end of the file", length, len(media))
+		}
+	}
+}
+
+func TestLocateContainerItems(t *testing.T) {
+	items := []containerItem{
+		{Semantic: "Primary", Offset: -1},
+		{Semantic: "MotionPhoto", Length: 10, Padding: 2, Offset: -1},
+		{Semantic: "Depth", Length: 4, Offset: -1},
+		{Semantic: "GainMap", Length: 1, Offset: -1},
+	}
+	locateContainerItems(items, 100, 116)
+	want := []int{0, 100, 112, -1}
+	for i, item := range items {
+		if item.Offset != want[i] {
+			t.Errorf("item %d: offset %d, want %d", i, item.Offset, want[i])
+		}
+	}
+}
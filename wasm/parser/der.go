@@ -0,0 +1,565 @@
+package parser
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A minimal DER reader for the X.509 certificates in C2PA signatures
+// Only the fields needed to name the signer, verify signatures and apply
+// the C2PA certificate profile are decoded: names, validity, the public
+// key and the basicConstraints, keyUsage and extKeyUsage extensions.
+
+// derValue is one DER-encoded TLV
+type derValue struct {
+	Tag     byte
+	Content []byte
+
+	// Raw is the complete encoding, header included
+	Raw []byte
+}
+
+// readDER decodes the value at the start of data and returns the rest
+func readDER(data []byte) (derValue, []byte, error) {
+	if len(data) < 2 {
+		return derValue{}, nil, fmt.Errorf("DER value truncated")
+	}
+	tag := data[0]
+	length := int(data[1])
+	header := 2
+	if length&0x80 != 0 {
+		n := length & 0x7F
+		if n == 0 || n > 4 || len(data) < 2+n {
+			return derValue{}, nil, fmt.Errorf("invalid DER length")
+		}
+		length = 0
+		for _, b := range data[2 : 2+n] {
+			length = length<<8 | int(b)
+		}
+		header += n
+	}
+	if length < 0 || length > len(data)-header {
+		return derValue{}, nil, fmt.Errorf("DER value truncated")
+	}
+	end := header + length
+	return derValue{Tag: tag, Content: data[header:end], Raw: data[:end]}, data[end:], nil
+}
+
+// children decodes the values of a SEQUENCE or SET
+func (v derValue) children() ([]derValue, error) {
+	var values []derValue
+	for rest := v.Content; len(rest) > 0; {
+		var child derValue
+		var err error
+		child, rest, err = readDER(rest)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, child)
+	}
+	return values, nil
+}
+
+// derOID formats an OBJECT IDENTIFIER as dotted decimal
+func derOID(content []byte) string {
+	if len(content) == 0 {
+		return ""
+	}
+	var parts []string
+	n := 0
+	for _, b := range content {
+		n = n<<7 | int(b&0x7F)
+		if b&0x80 != 0 {
+			continue
+		}
+		if len(parts) == 0 {
+			// The first subidentifier combines the first two arcs
+			first := n / 40
+			if first > 2 {
+				first = 2
+			}
+			parts = append(parts, strconv.Itoa(first), strconv.Itoa(n-first*40))
+		} else {
+			parts = append(parts, strconv.Itoa(n))
+		}
+		n = 0
+	}
+	return strings.Join(parts, ".")
+}
+
+// OIDs of the attributes, keys and signature algorithms used by C2PA
+const (
+	oidCommonName   = "2.5.4.3"
+	oidOrganization = "2.5.4.10"
+
+	oidECPublicKey = "1.2.840.10045.2.1"
+	oidRSAEncrypt  = "1.2.840.113549.1.1.1"
+	oidRSAPSS      = "1.2.840.113549.1.1.10"
+	oidEd25519     = "1.3.101.112"
+	oidCurveP256   = "1.2.840.10045.3.1.7"
+	oidCurveP384   = "1.3.132.0.34"
+	oidCurveP521   = "1.3.132.0.35"
+	oidSHA256      = "2.16.840.1.101.3.4.2.1"
+	oidSHA384      = "2.16.840.1.101.3.4.2.2"
+	oidSHA512      = "2.16.840.1.101.3.4.2.3"
+	oidECDSASHA256 = "1.2.840.10045.4.3.2"
+	oidECDSASHA384 = "1.2.840.10045.4.3.3"
+	oidECDSASHA512 = "1.2.840.10045.4.3.4"
+	oidRSASHA256   = "1.2.840.113549.1.1.11"
+	oidRSASHA384   = "1.2.840.113549.1.1.12"
+	oidRSASHA512   = "1.2.840.113549.1.1.13"
+
+	oidBasicConstraints = "2.5.29.19"
+	oidKeyUsage         = "2.5.29.15"
+	oidExtKeyUsage      = "2.5.29.37"
+)
+
+// keyUsage bits (RFC 5280 4.2.1.3)
+const (
+	keyUsageDigitalSignature = 0
+	keyUsageCertSign         = 5
+)
+
+// derCertificate is the decoded part of an X.509 certificate
+type derCertificate struct {
+	Raw []byte
+
+	// TBS is the signed tbsCertificate
+	TBS []byte
+
+	// Issuer and Subject are the DER-encoded names
+	Issuer  []byte
+	Subject []byte
+
+	KeyAlgorithm string
+	KeyParams    []byte
+	PublicKey    []byte
+
+	SignatureAlgorithm string
+	SignatureParams    []byte
+	Signature          []byte
+
+	NotBefore time.Time
+	NotAfter  time.Time
+
+	// IsCA is the cA flag of basicConstraints
+	IsCA bool
+
+	// KeyUsage holds the keyUsage bits, nil when the extension is absent
+	KeyUsage []byte
+
+	// ExtKeyUsage lists the extKeyUsage OIDs
+	ExtKeyUsage []string
+}
+
+// parseCertificate decodes a DER-encoded X.509 certificate
+func parseCertificate(data []byte) (*derCertificate, error) {
+	cert, _, err := readDER(data)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := cert.children()
+	if err != nil || cert.Tag != 0x30 || len(parts) != 3 {
+		return nil, fmt.Errorf("invalid certificate")
+	}
+	c := &derCertificate{Raw: cert.Raw, TBS: parts[0].Raw}
+
+	c.SignatureAlgorithm, c.SignatureParams = derAlgorithm(parts[1])
+	if parts[2].Tag != 0x03 || len(parts[2].Content) == 0 {
+		return nil, fmt.Errorf("invalid certificate signature")
+	}
+	c.Signature = parts[2].Content[1:]
+
+	tbs, err := parts[0].children()
+	if err != nil {
+		return nil, fmt.Errorf("invalid tbsCertificate: %w", err)
+	}
+	// The explicit [0] version is omitted for v1 certificates
+	if len(tbs) > 0 && tbs[0].Tag == 0xA0 {
+		tbs = tbs[1:]
+	}
+	// serialNumber, signature, issuer, validity, subject, subjectPublicKeyInfo
+	if len(tbs) < 6 {
+		return nil, fmt.Errorf("invalid tbsCertificate")
+	}
+	c.Issuer = tbs[2].Raw
+	c.Subject = tbs[4].Raw
+
+	spki, err := tbs[5].children()
+	if err != nil || len(spki) != 2 || spki[1].Tag != 0x03 || len(spki[1].Content) == 0 {
+		return nil, fmt.Errorf("invalid subjectPublicKeyInfo")
+	}
+	c.KeyAlgorithm, c.KeyParams = derAlgorithm(spki[0])
+	c.PublicKey = spki[1].Content[1:]
+
+	validity, err := tbs[3].children()
+	if err != nil || len(validity) != 2 {
+		return nil, fmt.Errorf("invalid certificate validity")
+	}
+	if c.NotBefore, err = derTime(validity[0]); err != nil {
+		return nil, err
+	}
+	if c.NotAfter, err = derTime(validity[1]); err != nil {
+		return nil, err
+	}
+
+	// Optional issuerUniqueID [1], subjectUniqueID [2] and extensions [3]
+	for _, field := range tbs[6:] {
+		if field.Tag == 0xA3 {
+			if err := c.readExtensions(field.Content); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// readExtensions decodes the extensions used by the C2PA profile
+func (c *derCertificate) readExtensions(data []byte) error {
+	seq, _, err := readDER(data)
+	if err != nil {
+		return fmt.Errorf("invalid certificate extensions")
+	}
+	extensions, err := seq.children()
+	if err != nil {
+		return fmt.Errorf("invalid certificate extensions")
+	}
+	for _, ext := range extensions {
+		parts, err := ext.children()
+		if err != nil || len(parts) < 2 || parts[0].Tag != 0x06 {
+			return fmt.Errorf("invalid certificate extension")
+		}
+		// The critical flag is optional; the value is the last OCTET STRING
+		value, _, err := readDER(parts[len(parts)-1].Content)
+		if err != nil {
+			return fmt.Errorf("invalid certificate extension")
+		}
+		switch derOID(parts[0].Content) {
+		case oidBasicConstraints:
+			fields, err := value.children()
+			if err != nil {
+				return fmt.Errorf("invalid basicConstraints")
+			}
+			c.IsCA = len(fields) > 0 && fields[0].Tag == 0x01 && len(fields[0].Content) == 1 && fields[0].Content[0] != 0
+		case oidKeyUsage:
+			if value.Tag != 0x03 || len(value.Content) == 0 {
+				return fmt.Errorf("invalid keyUsage")
+			}
+			c.KeyUsage = append([]byte{}, value.Content[1:]...)
+		case oidExtKeyUsage:
+			purposes, err := value.children()
+			if err != nil {
+				return fmt.Errorf("invalid extKeyUsage")
+			}
+			for _, purpose := range purposes {
+				c.ExtKeyUsage = append(c.ExtKeyUsage, derOID(purpose.Content))
+			}
+		}
+	}
+	return nil
+}
+
+// hasKeyUsage reports whether the keyUsage extension sets a bit; a
+// certificate without the extension allows every usage
+func (c *derCertificate) hasKeyUsage(bit int) bool {
+	if c.KeyUsage == nil {
+		return true
+	}
+	return bit/8 < len(c.KeyUsage) && c.KeyUsage[bit/8]&(0x80>>uint(bit%8)) != 0
+}
+
+// validAt reports whether t is within the certificate's validity period
+func (c *derCertificate) validAt(t time.Time) bool {
+	return !t.Before(c.NotBefore) && !t.After(c.NotAfter)
+}
+
+// derTime decodes a UTCTime or GeneralizedTime
+func derTime(v derValue) (time.Time, error) {
+	switch v.Tag {
+	case 0x17:
+		t, err := time.Parse("060102150405Z0700", string(v.Content))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid UTCTime")
+		}
+		// RFC 5280 maps two-digit years 50-99 to 19xx
+		if t.Year() >= 2050 {
+			t = t.AddDate(-100, 0, 0)
+		}
+		return t, nil
+	case 0x18:
+		t, err := time.Parse("20060102150405Z0700", string(v.Content))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid GeneralizedTime")
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid certificate time")
+}
+
+// derAlgorithm decodes an AlgorithmIdentifier into its OID and the
+// content of its parameters
+func derAlgorithm(v derValue) (string, []byte) {
+	parts, err := v.children()
+	if err != nil || len(parts) == 0 || parts[0].Tag != 0x06 {
+		return "", nil
+	}
+	if len(parts) > 1 {
+		return derOID(parts[0].Content), parts[1].Raw
+	}
+	return derOID(parts[0].Content), nil
+}
+
+// derNameAttribute returns the first attribute of a Name with the given
+// OID, or ""
+func derNameAttribute(name []byte, oid string) string {
+	seq, _, err := readDER(name)
+	if err != nil {
+		return ""
+	}
+	rdns, err := seq.children()
+	if err != nil {
+		return ""
+	}
+	for _, rdn := range rdns {
+		attrs, err := rdn.children()
+		if err != nil {
+			continue
+		}
+		for _, attr := range attrs {
+			parts, err := attr.children()
+			if err == nil && len(parts) == 2 && parts[0].Tag == 0x06 && derOID(parts[0].Content) == oid {
+				return string(parts[1].Content)
+			}
+		}
+	}
+	return ""
+}
+
+// derDisplayName names the holder of a Name by its organization or
+// common name
+func derDisplayName(name []byte) string {
+	cn := derNameAttribute(name, oidCommonName)
+	org := derNameAttribute(name, oidOrganization)
+	switch {
+	case org != "" && cn != "" && cn != org:
+		return org + " (" + cn + ")"
+	case org != "":
+		return org
+	}
+	return cn
+}
+
+// verify checks a signature made with the certificate's key
+// alg is a COSE algorithm name; ECDSA signatures are raw r||s values
+func (c *derCertificate) verify(alg string, message, signature []byte) error {
+	if len(alg) < 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	var hash crypto.Hash
+	switch alg[len(alg)-3:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	}
+	if alg == "Ed25519" {
+		if c.KeyAlgorithm != oidEd25519 || len(c.PublicKey) != ed25519.PublicKeySize {
+			return fmt.Errorf("certificate key is not Ed25519")
+		}
+		if !ed25519.Verify(ed25519.PublicKey(c.PublicKey), message, signature) {
+			return fmt.Errorf("signature does not match")
+		}
+		return nil
+	}
+	if hash == 0 {
+		return fmt.Errorf("unsupported algorithm %s", alg)
+	}
+	h := hash.New()
+	h.Write(message)
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "ES":
+		key, err := c.ecdsaKey()
+		if err != nil {
+			return err
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid ECDSA signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return fmt.Errorf("signature does not match")
+		}
+	case "PS", "RS":
+		key, err := c.rsaKey()
+		if err != nil {
+			return err
+		}
+		if alg[:2] == "PS" {
+			err = rsa.VerifyPSS(key, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+		} else {
+			err = rsa.VerifyPKCS1v15(key, hash, digest, signature)
+		}
+		if err != nil {
+			return fmt.Errorf("signature does not match")
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm %s", alg)
+	}
+	return nil
+}
+
+// ecdsaKey decodes an uncompressed EC public key
+func (c *derCertificate) ecdsaKey() (*ecdsa.PublicKey, error) {
+	if c.KeyAlgorithm != oidECPublicKey {
+		return nil, fmt.Errorf("certificate key is not ECDSA")
+	}
+	params, _, err := readDER(c.KeyParams)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EC parameters")
+	}
+	var curve elliptic.Curve
+	switch derOID(params.Content) {
+	case oidCurveP256:
+		curve = elliptic.P256()
+	case oidCurveP384:
+		curve = elliptic.P384()
+	case oidCurveP521:
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported EC curve")
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	if len(c.PublicKey) != 1+2*size || c.PublicKey[0] != 4 {
+		return nil, fmt.Errorf("unsupported EC point encoding")
+	}
+	return &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(c.PublicKey[1 : 1+size]),
+		Y:     new(big.Int).SetBytes(c.PublicKey[1+size:]),
+	}, nil
+}
+
+// rsaKey decodes an RSAPublicKey
+func (c *derCertificate) rsaKey() (*rsa.PublicKey, error) {
+	if c.KeyAlgorithm != oidRSAEncrypt && c.KeyAlgorithm != oidRSAPSS {
+		return nil, fmt.Errorf("certificate key is not RSA")
+	}
+	seq, _, err := readDER(c.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RSA key")
+	}
+	parts, err := seq.children()
+	if err != nil || len(parts) != 2 || parts[0].Tag != 0x02 || parts[1].Tag != 0x02 {
+		return nil, fmt.Errorf("invalid RSA key")
+	}
+	e := new(big.Int).SetBytes(parts[1].Content)
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("invalid RSA exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(parts[0].Content), E: int(e.Int64())}, nil
+}
+
+// checkSignedBy verifies that issuer signed the certificate
+func (c *derCertificate) checkSignedBy(issuer *derCertificate) error {
+	if !bytes.Equal(c.Issuer, issuer.Subject) {
+		return fmt.Errorf("issuer name does not match")
+	}
+	signature := c.Signature
+	alg := ""
+	switch c.SignatureAlgorithm {
+	case oidECDSASHA256, oidECDSASHA384, oidECDSASHA512:
+		alg = map[string]string{oidECDSASHA256: "ES256", oidECDSASHA384: "ES384", oidECDSASHA512: "ES512"}[c.SignatureAlgorithm]
+		key, err := issuer.ecdsaKey()
+		if err != nil {
+			return err
+		}
+		// Certificates hold DER ECDSA-Sig-Value sequences
+		signature, err = rawECDSASignature(signature, (key.Curve.Params().BitSize+7)/8)
+		if err != nil {
+			return err
+		}
+	case oidRSASHA256:
+		alg = "RS256"
+	case oidRSASHA384:
+		alg = "RS384"
+	case oidRSASHA512:
+		alg = "RS512"
+	case oidRSAPSS:
+		// The hash is named in the RSASSA-PSS-params; SHA-256 is assumed
+		// when the parameters cannot be read
+		alg = "PS256"
+		switch {
+		case bytes.Contains(c.SignatureParams, derOIDBytes(oidSHA384)):
+			alg = "PS384"
+		case bytes.Contains(c.SignatureParams, derOIDBytes(oidSHA512)):
+			alg = "PS512"
+		}
+	case oidEd25519:
+		alg = "Ed25519"
+	default:
+		return fmt.Errorf("unsupported certificate signature algorithm %s", c.SignatureAlgorithm)
+	}
+	return issuer.verify(alg, c.TBS, signature)
+}
+
+// rawECDSASignature converts an ECDSA-Sig-Value to fixed-size r||s
+func rawECDSASignature(der []byte, size int) ([]byte, error) {
+	seq, _, err := readDER(der)
+	if err != nil {
+		return nil, err
+	}
+	parts, err := seq.children()
+	if err != nil || len(parts) != 2 {
+		return nil, fmt.Errorf("invalid ECDSA signature")
+	}
+	raw := make([]byte, 2*size)
+	for i, part := range parts {
+		n := bytes.TrimLeft(part.Content, "\x00")
+		if len(n) > size {
+			return nil, fmt.Errorf("invalid ECDSA signature")
+		}
+		copy(raw[(i+1)*size-len(n):], n)
+	}
+	return raw, nil
+}
+
+// derOIDBytes encodes a dotted OID as the content of an OBJECT IDENTIFIER
+func derOIDBytes(oid string) []byte {
+	parts := strings.Split(oid, ".")
+	var out []byte
+	for i := 1; i < len(parts); i++ {
+		n, _ := strconv.Atoi(parts[i])
+		if i == 1 {
+			first, _ := strconv.Atoi(parts[0])
+			n += first * 40
+		}
+		var enc []byte
+		for {
+			enc = append([]byte{byte(n & 0x7F)}, enc...)
+			n >>= 7
+			if n == 0 {
+				break
+			}
+		}
+		for j := 0; j < len(enc)-1; j++ {
+			enc[j] |= 0x80
+		}
+		out = append(out, enc...)
+	}
+	return out
+}
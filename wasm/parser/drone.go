@@ -0,0 +1,150 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DJI MakerNote tags
+// The note is a plain IFD (offsets relative to the TIFF header) holding
+// the aircraft speed and attitude and the gimbal attitude as FLOATs
+var djiTags = tagTable{
+	0x0003: {Name: "FlightXSpeed", Type: typeFloat, Count: 1, Format: formatDroneSpeed},
+	0x0004: {Name: "FlightYSpeed", Type: typeFloat, Count: 1, Format: formatDroneSpeed},
+	0x0005: {Name: "FlightZSpeed", Type: typeFloat, Count: 1, Format: formatDroneSpeed},
+	0x0006: {Name: "FlightPitch", Type: typeFloat, Count: 1, Format: formatDroneAngle},
+	0x0007: {Name: "FlightYaw", Type: typeFloat, Count: 1, Format: formatDroneAngle},
+	0x0008: {Name: "FlightRoll", Type: typeFloat, Count: 1, Format: formatDroneAngle},
+	0x0009: {Name: "GimbalPitch", Type: typeFloat, Count: 1, Format: formatDroneAngle},
+	0x000A: {Name: "GimbalYaw", Type: typeFloat, Count: 1, Format: formatDroneAngle},
+	0x000B: {Name: "GimbalRoll", Type: typeFloat, Count: 1, Format: formatDroneAngle},
+}
+
+// parseDJIMakerNote decodes a DJI MakerNote into the Drone group
+func parseDJIMakerNote(p *SimpleExifParser, r *tiffReader, offset, size int) {
+	p.parseMakerNoteIFD(r, offset, GroupDrone, djiTags, nil)
+}
+
+// droneXMPProperties maps the drone-dji XMP properties to Drone entries
+// and their formatters
+var droneXMPProperties = []struct {
+	property string
+	name     string
+	format   func(v float64) string
+}{
+	{"GimbalRollDegree", "GimbalRoll", droneAngle},
+	{"GimbalPitchDegree", "GimbalPitch", droneAngle},
+	{"GimbalYawDegree", "GimbalYaw", droneAngle},
+	{"FlightRollDegree", "FlightRoll", droneAngle},
+	{"FlightPitchDegree", "FlightPitch", droneAngle},
+	{"FlightYawDegree", "FlightYaw", droneAngle},
+	{"FlightXSpeed", "FlightXSpeed", droneSpeed},
+	{"FlightYSpeed", "FlightYSpeed", droneSpeed},
+	{"FlightZSpeed", "FlightZSpeed", droneSpeed},
+	{"RelativeAltitude", "RelativeAltitude", droneMeters},
+	{"AbsoluteAltitude", "AbsoluteAltitude", droneMeters},
+	{"LRFTargetDistance", "LaserRangeDistance", droneMeters},
+}
+
+// reportDrone collects drone attitude data into the Drone group: the DJI
+// MakerNote values (decoded while parsing EXIF) are completed with the
+// drone-dji XMP properties, then combined with the GPS position and
+// summarised as the direction the camera was pointing
+func reportDrone(md *Metadata) {
+	for _, prop := range droneXMPProperties {
+		if md.Get(GroupDrone, prop.name) != nil {
+			continue
+		}
+		e := md.Get(GroupXMP, "drone-dji:"+prop.property)
+		if e == nil {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimPrefix(e.Value, "+"), 64)
+		if err != nil {
+			continue
+		}
+		md.SetRaw(GroupDrone, prop.name, []float64{v}, prop.format(v), e.Offset, "drone-dji")
+	}
+	for _, name := range []string{"RtkFlag", "CamReverse", "GimbalReverse"} {
+		if e := md.Get(GroupXMP, "drone-dji:"+name); e != nil {
+			md.Set(GroupDrone, name, e.Value, e.Offset, "drone-dji")
+		}
+	}
+
+	if !md.hasGroup(GroupDrone) {
+		return
+	}
+
+	for _, name := range []string{"GPSLatitude", "GPSLongitude", "GPSAltitude"} {
+		if e := md.Get(GroupGPS, name); e != nil {
+			md.Set(GroupDrone, name, e.Value, e.Offset, e.Source)
+		}
+	}
+	if absolute, ok := droneValue(md, "AbsoluteAltitude"); ok {
+		if relative, ok := droneValue(md, "RelativeAltitude"); ok {
+			md.SetRaw(GroupDrone, "TakeoffAltitude", []float64{absolute - relative}, droneMeters(absolute-relative), -1, "drone-dji")
+		}
+	}
+
+	if yaw, ok := droneValue(md, "GimbalYaw"); ok {
+		heading := math.Mod(yaw+360, 360)
+		md.Set(GroupDrone, "CameraHeading", fmt.Sprintf("%s° (%s)", formatDecimal(heading, 1), compassPoint(heading)), -1, "derived")
+	}
+	if pitch, ok := droneValue(md, "GimbalPitch"); ok {
+		tilt := formatDecimal(pitch, 1) + "°"
+		switch {
+		case pitch <= -89.5:
+			tilt += " (nadir)"
+		case pitch < 0:
+			tilt += " (below horizon)"
+		case pitch > 0:
+			tilt += " (above horizon)"
+		}
+		md.Set(GroupDrone, "CameraTilt", tilt, -1, "derived")
+	}
+}
+
+// droneValue returns the numeric value of a Drone entry
+func droneValue(md *Metadata, name string) (float64, bool) {
+	e := md.Get(GroupDrone, name)
+	if e == nil {
+		return 0, false
+	}
+	return firstFloat(e.Raw)
+}
+
+// compassPoint names the 8-point compass direction of a heading in degrees
+func compassPoint(heading float64) string {
+	points := []string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+	return points[int(math.Floor(heading/45+0.5))%8]
+}
+
+func droneAngle(v float64) string {
+	return formatDecimal(v, 2) + "°"
+}
+
+func droneSpeed(v float64) string {
+	return formatDecimal(v, 2) + " m/s"
+}
+
+func droneMeters(v float64) string {
+	return formatDecimal(v, 2) + " m"
+}
+
+func formatDroneAngle(value interface{}) string {
+	v, ok := firstFloat(value)
+	if !ok {
+		return ""
+	}
+	return droneAngle(v)
+}
+
+func formatDroneSpeed(value interface{}) string {
+	v, ok := firstFloat(value)
+	if !ok {
+		return ""
+	}
+	return droneSpeed(v)
+}
@@ -1,5 +1,7 @@
 package parser
 
+import "fmt"
+
 // ExifParser handles JPEG and TIFF formats with EXIF data
 // This is an alias for SimpleExifParser (standard library only)
 type ExifParser struct {
@@ -7,10 +9,29 @@ type ExifParser struct {
 }
 
 // Parse extracts EXIF data from JPEG or TIFF images
-func (p *ExifParser) Parse(data []byte) (ExifData, error) {
+// TIFF files are a bare TIFF structure, so they go straight to ParseTIFF
+func (p *ExifParser) Parse(data []byte) (*Metadata, error) {
+	if DetectFormat(data) == FormatTIFF {
+		return p.parseTIFFFile(data)
+	}
 	return p.SimpleExifParser.Parse(data)
 }
 
+// parseTIFFFile extracts metadata from a standalone TIFF file
+func (p *ExifParser) parseTIFFFile(data []byte) (*Metadata, error) {
+	md := NewMetadata(FormatTIFF)
+
+	if err := p.ParseTIFF(data, 0, "TIFF", md); err != nil {
+		return nil, fmt.Errorf("failed to parse TIFF: %w", err)
+	}
+
+	if md.Len() == 0 {
+		return nil, fmt.Errorf("no metadata found in TIFF")
+	}
+
+	return md, nil
+}
+
 // SupportsFormat checks if this parser supports the given format
 func (p *ExifParser) SupportsFormat(format ImageFormat) bool {
 	return format == FormatJPEG || format == FormatTIFF
@@ -0,0 +1,526 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Enumerated tag values (CIPA DC-008 / TIFF 6.0)
+var (
+	orientationNames = map[int]string{
+		1: "Horizontal (normal)",
+		2: "Mirror horizontal",
+		3: "Rotate 180",
+		4: "Mirror vertical",
+		5: "Mirror horizontal and rotate 270 CW",
+		6: "Rotate 90 CW",
+		7: "Mirror horizontal and rotate 90 CW",
+		8: "Rotate 270 CW",
+	}
+
+	compressionNames = map[int]string{
+		1:     "Uncompressed",
+		2:     "CCITT 1D",
+		3:     "T4/Group 3 Fax",
+		4:     "T6/Group 4 Fax",
+		5:     "LZW",
+		6:     "JPEG (old-style)",
+		7:     "JPEG",
+		8:     "Adobe Deflate",
+		32773: "PackBits",
+		32946: "Deflate",
+		34892: "Lossy JPEG",
+		50001: "WebP",
+	}
+
+	photometricNames = map[int]string{
+		0:     "WhiteIsZero",
+		1:     "BlackIsZero",
+		2:     "RGB",
+		3:     "RGB Palette",
+		4:     "Transparency Mask",
+		5:     "CMYK",
+		6:     "YCbCr",
+		8:     "CIELab",
+		9:     "ICCLab",
+		10:    "ITULab",
+		32803: "Color Filter Array",
+		34892: "Linear Raw",
+	}
+
+	planarConfigurationNames = map[int]string{
+		1: "Chunky",
+		2: "Planar",
+	}
+
+	resolutionUnitNames = map[int]string{
+		1: "None",
+		2: "inches",
+		3: "cm",
+	}
+
+	yCbCrPositioningNames = map[int]string{
+		1: "Centered",
+		2: "Co-sited",
+	}
+
+	exposureProgramNames = map[int]string{
+		0: "Not Defined",
+		1: "Manual",
+		2: "Program AE",
+		3: "Aperture-priority AE",
+		4: "Shutter speed priority AE",
+		5: "Creative (Slow speed)",
+		6: "Action (High speed)",
+		7: "Portrait",
+		8: "Landscape",
+		9: "Bulb",
+	}
+
+	sensitivityTypeNames = map[int]string{
+		0: "Unknown",
+		1: "Standard Output Sensitivity",
+		2: "Recommended Exposure Index",
+		3: "ISO Speed",
+		4: "Standard Output Sensitivity and Recommended Exposure Index",
+		5: "Standard Output Sensitivity and ISO Speed",
+		6: "Recommended Exposure Index and ISO Speed",
+		7: "Standard Output Sensitivity, Recommended Exposure Index and ISO Speed",
+	}
+
+	meteringModeNames = map[int]string{
+		0:   "Unknown",
+		1:   "Average",
+		2:   "Center-weighted average",
+		3:   "Spot",
+		4:   "Multi-spot",
+		5:   "Multi-segment",
+		6:   "Partial",
+		255: "Other",
+	}
+
+	lightSourceNames = map[int]string{
+		0:   "Unknown",
+		1:   "Daylight",
+		2:   "Fluorescent",
+		3:   "Tungsten (Incandescent)",
+		4:   "Flash",
+		9:   "Fine Weather",
+		10:  "Cloudy",
+		11:  "Shade",
+		12:  "Daylight Fluorescent",
+		13:  "Day White Fluorescent",
+		14:  "Cool White Fluorescent",
+		15:  "White Fluorescent",
+		16:  "Warm White Fluorescent",
+		17:  "Standard Light A",
+		18:  "Standard Light B",
+		19:  "Standard Light C",
+		20:  "D55",
+		21:  "D65",
+		22:  "D75",
+		23:  "D50",
+		24:  "ISO Studio Tungsten",
+		255: "Other",
+	}
+
+	colorSpaceNames = map[int]string{
+		1:      "sRGB",
+		2:      "Adobe RGB",
+		0xFFFD: "Wide Gamut RGB",
+		0xFFFE: "ICC Profile",
+		0xFFFF: "Uncalibrated",
+	}
+
+	focalPlaneResolutionUnitNames = map[int]string{
+		1: "None",
+		2: "inches",
+		3: "cm",
+		4: "mm",
+		5: "um",
+	}
+
+	sensingMethodNames = map[int]string{
+		1: "Not defined",
+		2: "One-chip color area",
+		3: "Two-chip color area",
+		4: "Three-chip color area",
+		5: "Color sequential area",
+		7: "Trilinear",
+		8: "Color sequential linear",
+	}
+
+	fileSourceNames = map[int]string{
+		0: "Others",
+		1: "Film Scanner",
+		2: "Reflection Print Scanner",
+		3: "Digital Camera",
+	}
+
+	sceneTypeNames = map[int]string{
+		1: "Directly photographed",
+	}
+
+	customRenderedNames = map[int]string{
+		0: "Normal",
+		1: "Custom",
+		2: "HDR (no original saved)",
+		3: "HDR (original saved)",
+		4: "Original (for HDR)",
+		6: "Panorama",
+		7: "Portrait HDR",
+		8: "Portrait",
+	}
+
+	exposureModeNames = map[int]string{
+		0: "Auto",
+		1: "Manual",
+		2: "Auto bracket",
+	}
+
+	whiteBalanceNames = map[int]string{
+		0: "Auto",
+		1: "Manual",
+	}
+
+	sceneCaptureTypeNames = map[int]string{
+		0: "Standard",
+		1: "Landscape",
+		2: "Portrait",
+		3: "Night",
+		4: "Other",
+	}
+
+	gainControlNames = map[int]string{
+		0: "None",
+		1: "Low gain up",
+		2: "High gain up",
+		3: "Low gain down",
+		4: "High gain down",
+	}
+
+	contrastNames = map[int]string{
+		0: "Normal",
+		1: "Low",
+		2: "High",
+	}
+
+	saturationNames = map[int]string{
+		0: "Normal",
+		1: "Low",
+		2: "High",
+	}
+
+	sharpnessNames = map[int]string{
+		0: "Normal",
+		1: "Soft",
+		2: "Hard",
+	}
+
+	subjectDistanceRangeNames = map[int]string{
+		0: "Unknown",
+		1: "Macro",
+		2: "Close",
+		3: "Distant",
+	}
+
+	compositeImageNames = map[int]string{
+		0: "Unknown",
+		1: "Not a Composite Image",
+		2: "General Composite Image",
+		3: "Composite Image Captured While Shooting",
+	}
+)
+
+// formatEnum returns a formatter that maps a numeric code to its name
+func formatEnum(names map[int]string) func(value interface{}) string {
+	return func(value interface{}) string {
+		code, ok := firstInt(value)
+		if !ok {
+			return formatTagValue(value)
+		}
+		if name, ok := names[code]; ok {
+			return name
+		}
+		return fmt.Sprintf("Unknown (%d)", code)
+	}
+}
+
+// firstInt returns the first element of an integer value
+// UNDEFINED values (e.g. FileSource) are treated as bytes
+func firstInt(value interface{}) (int, bool) {
+	if raw, ok := value.([]byte); ok {
+		if len(raw) == 0 {
+			return 0, false
+		}
+		return int(raw[0]), true
+	}
+	values := valueFloats(value)
+	if len(values) == 0 {
+		return 0, false
+	}
+	return int(values[0]), true
+}
+
+// firstFloat returns the first element of a numeric value
+func firstFloat(value interface{}) (float64, bool) {
+	values := valueFloats(value)
+	if len(values) == 0 {
+		return 0, false
+	}
+	return values[0], true
+}
+
+// formatDecimal renders a number with at most the given number of decimals,
+// dropping trailing zeros ("2.8" rather than "2.80")
+func formatDecimal(v float64, decimals int) string {
+	text := strconv.FormatFloat(v, 'f', decimals, 64)
+	if strings.Contains(text, ".") {
+		text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	}
+	if text == "-0" {
+		text = "0"
+	}
+	return text
+}
+
+// formatExposureTime renders an exposure time as "1/8000 s" or "2.5 s"
+func formatExposureTime(value interface{}) string {
+	if r, ok := value.([]Rational); ok && len(r) > 0 && r[0].Num == 1 && r[0].Den > 1 {
+		return fmt.Sprintf("1/%d s", r[0].Den)
+	}
+
+	seconds, ok := firstFloat(value)
+	if !ok || seconds <= 0 {
+		return formatTagValue(value)
+	}
+	return formatSeconds(seconds)
+}
+
+// formatSeconds renders a duration in seconds, using a 1/x fraction below 0.25 s
+func formatSeconds(seconds float64) string {
+	if seconds < 0.25 {
+		return fmt.Sprintf("1/%d s", int(math.Round(1/seconds)))
+	}
+	return formatDecimal(seconds, 1) + " s"
+}
+
+// formatFNumber renders an f-number as "f/2.8"
+func formatFNumber(value interface{}) string {
+	fNumber, ok := firstFloat(value)
+	if !ok || fNumber <= 0 {
+		return formatTagValue(value)
+	}
+	return "f/" + formatDecimal(fNumber, 1)
+}
+
+// formatAPEXAperture converts an APEX aperture value to an f-number
+func formatAPEXAperture(value interface{}) string {
+	av, ok := firstFloat(value)
+	if !ok {
+		return formatTagValue(value)
+	}
+	return "f/" + formatDecimal(math.Pow(2, av/2), 1)
+}
+
+// formatAPEXShutterSpeed converts an APEX shutter speed value to an exposure time
+func formatAPEXShutterSpeed(value interface{}) string {
+	tv, ok := firstFloat(value)
+	if !ok {
+		return formatTagValue(value)
+	}
+	return formatSeconds(math.Pow(2, -tv))
+}
+
+// formatMillimeters renders a focal length as "50 mm"
+func formatMillimeters(value interface{}) string {
+	mm, ok := firstFloat(value)
+	if !ok {
+		return formatTagValue(value)
+	}
+	return formatDecimal(mm, 1) + " mm"
+}
+
+// formatEV renders an exposure value as "+0.7 EV"
+func formatEV(value interface{}) string {
+	ev, ok := firstFloat(value)
+	if !ok {
+		return formatTagValue(value)
+	}
+	text := formatDecimal(ev, 2)
+	if ev > 0 {
+		text = "+" + text
+	}
+	return text + " EV"
+}
+
+// formatUnit returns a formatter that appends a unit to a decimal value
+func formatUnit(unit string, decimals int) func(value interface{}) string {
+	return func(value interface{}) string {
+		v, ok := firstFloat(value)
+		if !ok {
+			return formatTagValue(value)
+		}
+		return formatDecimal(v, decimals) + " " + unit
+	}
+}
+
+// formatSubjectDistance renders a subject distance in meters
+// (0 = unknown, 0xFFFFFFFF = infinity)
+func formatSubjectDistance(value interface{}) string {
+	if r, ok := value.([]Rational); ok && len(r) > 0 {
+		switch {
+		case r[0].Num == 0:
+			return "Unknown"
+		case r[0].Num == 0xFFFFFFFF:
+			return "Infinity"
+		}
+	}
+	return formatUnit("m", 2)(value)
+}
+
+// formatVersion renders a 4-character version such as "0232" as "2.32"
+func formatVersion(value interface{}) string {
+	version := valueString(value)
+	if len(version) != 4 {
+		return formatTagValue(value)
+	}
+	major := strings.TrimLeft(version[0:2], "0")
+	if major == "" {
+		major = "0"
+	}
+	minor := strings.TrimRight(version[2:4], "0")
+	if minor == "" {
+		return major + ".0"
+	}
+	return major + "." + minor
+}
+
+// formatComponentsConfiguration renders the channel order (e.g. "Y, Cb, Cr")
+func formatComponentsConfiguration(value interface{}) string {
+	raw, ok := value.([]byte)
+	if !ok {
+		return formatTagValue(value)
+	}
+
+	names := []string{"-", "Y", "Cb", "Cr", "R", "G", "B"}
+	parts := make([]string, 0, len(raw))
+	for _, c := range raw {
+		if c == 0 {
+			continue
+		}
+		if int(c) < len(names) {
+			parts = append(parts, names[c])
+		} else {
+			parts = append(parts, strconv.Itoa(int(c)))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatFlash decodes the Flash bitfield
+// bit 0: fired, bits 1-2: return light, bits 3-4: mode,
+// bit 5: no flash function, bit 6: red-eye reduction
+func formatFlash(value interface{}) string {
+	code, ok := firstInt(value)
+	if !ok {
+		return formatTagValue(value)
+	}
+
+	if code&0x20 != 0 {
+		return "No flash function"
+	}
+
+	var parts []string
+	if code&0x01 != 0 {
+		parts = append(parts, "Fired")
+	} else {
+		parts = append(parts, "Did not fire")
+	}
+
+	switch (code >> 1) & 0x03 {
+	case 2:
+		parts = append(parts, "Return not detected")
+	case 3:
+		parts = append(parts, "Return detected")
+	}
+
+	switch (code >> 3) & 0x03 {
+	case 1:
+		parts = append(parts, "Compulsory flash firing")
+	case 2:
+		parts = append(parts, "Compulsory flash suppression")
+	case 3:
+		parts = append(parts, "Auto mode")
+	}
+
+	if code&0x40 != 0 {
+		parts = append(parts, "Red-eye reduction")
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// formatLensSpecification renders min/max focal length and aperture
+// as "24-70 mm f/2.8" (0/0 entries mean unknown)
+func formatLensSpecification(value interface{}) string {
+	spec, ok := value.([]Rational)
+	if !ok || len(spec) < 4 {
+		return formatTagValue(value)
+	}
+
+	focal := formatDecimal(spec[0].Float(), 1)
+	if spec[1].Float() != spec[0].Float() {
+		focal += "-" + formatDecimal(spec[1].Float(), 1)
+	}
+	text := focal + " mm"
+
+	if spec[2].Den != 0 {
+		aperture := formatDecimal(spec[2].Float(), 1)
+		if spec[3].Den != 0 && spec[3].Float() != spec[2].Float() {
+			aperture += "-" + formatDecimal(spec[3].Float(), 1)
+		}
+		text += " f/" + aperture
+	}
+	return text
+}
+
+// formatSubjectArea describes the subject location by its number of values
+// 2 = point, 3 = circle, 4 = rectangle
+func formatSubjectArea(value interface{}) string {
+	v := valueFloats(value)
+	switch len(v) {
+	case 2:
+		return fmt.Sprintf("Point (%g, %g)", v[0], v[1])
+	case 3:
+		return fmt.Sprintf("Circle center (%g, %g), diameter %g", v[0], v[1], v[2])
+	case 4:
+		return fmt.Sprintf("Rectangle center (%g, %g), %gx%g", v[0], v[1], v[2], v[3])
+	}
+	return formatTagValue(value)
+}
+
+// formatResolution renders a resolution without trailing zeros ("72")
+func formatResolution(value interface{}) string {
+	v, ok := firstFloat(value)
+	if !ok {
+		return formatTagValue(value)
+	}
+	return formatDecimal(v, 2)
+}
+
+// formatInteropIndex describes the DCF rule set declared by InteroperabilityIndex
+func formatInteropIndex(value interface{}) string {
+	index := valueString(value)
+	switch index {
+	case "R98":
+		return "R98 - DCF basic file (sRGB)"
+	case "R03":
+		return "R03 - DCF option file (Adobe RGB)"
+	case "THM":
+		return "THM - DCF thumbnail file"
+	}
+	return index
+}
@@ -0,0 +1,370 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// ifdKind identifies which IFD a tag was found in
+// Tag IDs are only unique within one IFD (e.g. 0x0001 is GPSLatitudeRef
+// in the GPS IFD but InteroperabilityIndex in the Interoperability IFD)
+type ifdKind int
+
+const (
+	ifdIFD0 ifdKind = iota
+	ifdExif
+	ifdGPS
+	ifdInterop
+	ifdIFD1
+)
+
+// tagInfo describes a tag from CIPA DC-008 (EXIF 2.32 / 3.0) or TIFF 6.0
+type tagInfo struct {
+	Name string
+
+	// Type is the field type defined by the specification (0 = any)
+	// Writers do not always follow it, so values are decoded from the
+	// type stored in the entry and a mismatch is only reported
+	Type uint16
+
+	// Count is the number of values defined by the specification (0 = any)
+	Count int
+
+	// Format renders the decoded value; nil uses formatTagValue
+	Format func(value interface{}) string
+}
+
+// tagTable maps tag IDs to their description for one IFD
+type tagTable map[uint16]tagInfo
+
+// lookupTag returns the description of a tag in the given IFD
+// Unknown tags are named after their ID (e.g. "Tag_0xA500")
+func lookupTag(kind ifdKind, tag uint16) tagInfo {
+	table := ifd0Tags
+	switch kind {
+	case ifdExif:
+		table = exifTags
+	case ifdGPS:
+		table = gpsTags
+	case ifdInterop:
+		table = interopTags
+	}
+
+	if info, ok := table[tag]; ok {
+		return info
+	}
+	return tagInfo{Name: fmt.Sprintf("Tag_0x%04X", tag)}
+}
+
+// typeNames names the TIFF field types in format warnings
+var typeNames = map[int]string{
+	typeByte:      "BYTE",
+	typeASCII:     "ASCII",
+	typeShort:     "SHORT",
+	typeLong:      "LONG",
+	typeRational:  "RATIONAL",
+	typeSByte:     "SBYTE",
+	typeUndefined: "UNDEFINED",
+	typeSShort:    "SSHORT",
+	typeSLong:     "SLONG",
+	typeSRational: "SRATIONAL",
+	typeFloat:     "FLOAT",
+	typeDouble:    "DOUBLE",
+	typeIFD:       "IFD",
+	typeUTF8:      "UTF-8",
+}
+
+// formatMismatch describes how a stored type and count differ from the
+// specification, or returns "" when they agree. SHORT is accepted for
+// LONG fields (TIFF 6.0 allows either for sizes and offsets), BYTE and
+// UNDEFINED for each other, LONG for variable-length UNDEFINED blocks
+// (Photoshop writes IPTC-NAA that way) and UTF-8 for ASCII (EXIF 3.0);
+// text counts may leave out the terminating NUL
+func (info tagInfo) formatMismatch(dataType uint16, count uint32) string {
+	var problems []string
+
+	text := dataType == typeASCII || dataType == typeUTF8
+	if info.Type != 0 && dataType != info.Type {
+		compatible := false
+		switch info.Type {
+		case typeLong:
+			compatible = dataType == typeShort
+		case typeByte:
+			compatible = dataType == typeUndefined
+		case typeUndefined:
+			compatible = dataType == typeByte || dataType == typeLong && info.Count == 0
+		case typeASCII, typeUTF8:
+			compatible = text
+		}
+		if !compatible {
+			problems = append(problems, fmt.Sprintf("type %s, expected %s",
+				lookupCode(typeNames, int(dataType)), lookupCode(typeNames, int(info.Type))))
+		}
+	}
+
+	if info.Count != 0 && int64(count) != int64(info.Count) && !(text && int64(count) == int64(info.Count)-1) {
+		problems = append(problems, fmt.Sprintf("count %d, expected %d", count, info.Count))
+	}
+	return strings.Join(problems, "; ")
+}
+
+// ifd0Tags covers IFD0 and IFD1 (TIFF 6.0 baseline/extension tags plus
+// the EXIF-defined IFD0 tags)
+var ifd0Tags = tagTable{
+	0x000B: {Name: "ProcessingSoftware", Type: typeASCII},
+	0x00FE: {Name: "NewSubfileType", Type: typeLong, Count: 1},
+	0x00FF: {Name: "SubfileType", Type: typeShort, Count: 1},
+	0x0100: {Name: "ImageWidth", Type: typeLong, Count: 1},
+	0x0101: {Name: "ImageLength", Type: typeLong, Count: 1},
+	0x0102: {Name: "BitsPerSample", Type: typeShort},
+	0x0103: {Name: "Compression", Type: typeShort, Count: 1, Format: formatEnum(compressionNames)},
+	0x0106: {Name: "PhotometricInterpretation", Type: typeShort, Count: 1, Format: formatEnum(photometricNames)},
+	0x0107: {Name: "Thresholding", Type: typeShort, Count: 1},
+	0x0108: {Name: "CellWidth", Type: typeShort, Count: 1},
+	0x0109: {Name: "CellLength", Type: typeShort, Count: 1},
+	0x010A: {Name: "FillOrder", Type: typeShort, Count: 1},
+	0x010D: {Name: "DocumentName", Type: typeASCII},
+	0x010E: {Name: "ImageDescription", Type: typeASCII},
+	0x010F: {Name: "Make", Type: typeASCII},
+	0x0110: {Name: "Model", Type: typeASCII},
+	0x0111: {Name: "StripOffsets", Type: typeLong, Format: formatCount("strip", "strips")},
+	0x0112: {Name: "Orientation", Type: typeShort, Count: 1, Format: formatEnum(orientationNames)},
+	0x0115: {Name: "SamplesPerPixel", Type: typeShort, Count: 1},
+	0x0116: {Name: "RowsPerStrip", Type: typeLong, Count: 1},
+	0x0117: {Name: "StripByteCounts", Type: typeLong, Format: formatCount("strip", "strips")},
+	0x0118: {Name: "MinSampleValue", Type: typeShort},
+	0x0119: {Name: "MaxSampleValue", Type: typeShort},
+	0x011A: {Name: "XResolution", Type: typeRational, Count: 1, Format: formatResolution},
+	0x011B: {Name: "YResolution", Type: typeRational, Count: 1, Format: formatResolution},
+	0x011C: {Name: "PlanarConfiguration", Type: typeShort, Count: 1, Format: formatEnum(planarConfigurationNames)},
+	0x011D: {Name: "PageName", Type: typeASCII},
+	0x011E: {Name: "XPosition", Type: typeRational, Count: 1},
+	0x011F: {Name: "YPosition", Type: typeRational, Count: 1},
+	0x0122: {Name: "GrayResponseUnit", Type: typeShort, Count: 1},
+	0x0123: {Name: "GrayResponseCurve", Type: typeShort},
+	0x0124: {Name: "T4Options", Type: typeLong, Count: 1},
+	0x0125: {Name: "T6Options", Type: typeLong, Count: 1},
+	0x0128: {Name: "ResolutionUnit", Type: typeShort, Count: 1, Format: formatEnum(resolutionUnitNames)},
+	0x0129: {Name: "PageNumber", Type: typeShort, Count: 2},
+	0x012D: {Name: "TransferFunction", Type: typeShort, Count: 768},
+	0x0131: {Name: "Software", Type: typeASCII},
+	0x0132: {Name: "DateTime", Type: typeASCII, Count: 20},
+	0x013B: {Name: "Artist", Type: typeASCII},
+	0x013C: {Name: "HostComputer", Type: typeASCII},
+	0x013D: {Name: "Predictor", Type: typeShort, Count: 1},
+	0x013E: {Name: "WhitePoint", Type: typeRational, Count: 2},
+	0x013F: {Name: "PrimaryChromaticities", Type: typeRational, Count: 6},
+	0x0140: {Name: "ColorMap", Type: typeShort, Format: formatCount("entry", "entries")},
+	0x0141: {Name: "HalftoneHints", Type: typeShort, Count: 2},
+	0x0142: {Name: "TileWidth", Type: typeLong, Count: 1},
+	0x0143: {Name: "TileLength", Type: typeLong, Count: 1},
+	0x0144: {Name: "TileOffsets", Type: typeLong, Format: formatCount("tile", "tiles")},
+	0x0145: {Name: "TileByteCounts", Type: typeLong, Format: formatCount("tile", "tiles")},
+	0x014A: {Name: "SubIFDs", Type: typeLong},
+	0x014C: {Name: "InkSet", Type: typeShort, Count: 1},
+	0x014D: {Name: "InkNames", Type: typeASCII},
+	0x014E: {Name: "NumberOfInks", Type: typeShort, Count: 1},
+	0x0151: {Name: "TargetPrinter", Type: typeASCII},
+	0x0152: {Name: "ExtraSamples", Type: typeShort},
+	0x0153: {Name: "SampleFormat", Type: typeShort},
+	0x0154: {Name: "SMinSampleValue"},
+	0x0155: {Name: "SMaxSampleValue"},
+	0x0156: {Name: "TransferRange", Type: typeShort, Count: 6},
+	0x0200: {Name: "JPEGProc", Type: typeShort, Count: 1},
+	0x0201: {Name: "JPEGInterchangeFormat", Type: typeLong, Count: 1},
+	0x0202: {Name: "JPEGInterchangeFormatLength", Type: typeLong, Count: 1},
+	0x0211: {Name: "YCbCrCoefficients", Type: typeRational, Count: 3},
+	0x0212: {Name: "YCbCrSubSampling", Type: typeShort, Count: 2},
+	0x0213: {Name: "YCbCrPositioning", Type: typeShort, Count: 1, Format: formatEnum(yCbCrPositioningNames)},
+	0x0214: {Name: "ReferenceBlackWhite", Type: typeRational, Count: 6},
+	0x02BC: {Name: "ApplicationNotes", Type: typeByte, Format: formatByteSize},
+	0x4746: {Name: "Rating", Type: typeShort, Count: 1},
+	0x4749: {Name: "RatingPercent", Type: typeShort, Count: 1},
+	0x8298: {Name: "Copyright", Type: typeASCII},
+	0x83BB: {Name: "IPTC-NAA", Type: typeUndefined, Format: formatByteSize},
+	0x8649: {Name: "ImageResources", Type: typeByte, Format: formatByteSize},
+	0x8769: {Name: "ExifIFDPointer", Type: typeLong, Count: 1},
+	0x8773: {Name: "InterColorProfile", Type: typeUndefined, Format: formatByteSize},
+	0x8825: {Name: "GPSInfoIFDPointer", Type: typeLong, Count: 1},
+	0x9C9B: {Name: "XPTitle", Type: typeByte, Format: formatXPString},
+	0x9C9C: {Name: "XPComment", Type: typeByte, Format: formatXPString},
+	0x9C9D: {Name: "XPAuthor", Type: typeByte, Format: formatXPString},
+	0x9C9E: {Name: "XPKeywords", Type: typeByte, Format: formatXPString},
+	0x9C9F: {Name: "XPSubject", Type: typeByte, Format: formatXPString},
+	0xC4A5: {Name: "PrintImageMatching", Type: typeUndefined, Format: formatByteSize},
+}
+
+// exifTags covers the Exif IFD
+var exifTags = tagTable{
+	0x829A: {Name: "ExposureTime", Type: typeRational, Count: 1, Format: formatExposureTime},
+	0x829D: {Name: "FNumber", Type: typeRational, Count: 1, Format: formatFNumber},
+	0x8822: {Name: "ExposureProgram", Type: typeShort, Count: 1, Format: formatEnum(exposureProgramNames)},
+	0x8824: {Name: "SpectralSensitivity", Type: typeASCII},
+	0x8827: {Name: "ISO", Type: typeShort},
+	0x8828: {Name: "OECF", Type: typeUndefined, Format: formatByteSize},
+	0x8830: {Name: "SensitivityType", Type: typeShort, Count: 1, Format: formatEnum(sensitivityTypeNames)},
+	0x8831: {Name: "StandardOutputSensitivity", Type: typeLong, Count: 1},
+	0x8832: {Name: "RecommendedExposureIndex", Type: typeLong, Count: 1},
+	0x8833: {Name: "ISOSpeed", Type: typeLong, Count: 1},
+	0x8834: {Name: "ISOSpeedLatitudeyyy", Type: typeLong, Count: 1},
+	0x8835: {Name: "ISOSpeedLatitudezzz", Type: typeLong, Count: 1},
+	0x9000: {Name: "ExifVersion", Type: typeUndefined, Count: 4, Format: formatVersion},
+	0x9003: {Name: "DateTimeOriginal", Type: typeASCII, Count: 20},
+	0x9004: {Name: "DateTimeDigitized", Type: typeASCII, Count: 20},
+	0x9010: {Name: "OffsetTime", Type: typeASCII, Count: 7},
+	0x9011: {Name: "OffsetTimeOriginal", Type: typeASCII, Count: 7},
+	0x9012: {Name: "OffsetTimeDigitized", Type: typeASCII, Count: 7},
+	0x9101: {Name: "ComponentsConfiguration", Type: typeUndefined, Count: 4, Format: formatComponentsConfiguration},
+	0x9102: {Name: "CompressedBitsPerPixel", Type: typeRational, Count: 1},
+	0x9201: {Name: "ShutterSpeedValue", Type: typeSRational, Count: 1, Format: formatAPEXShutterSpeed},
+	0x9202: {Name: "ApertureValue", Type: typeRational, Count: 1, Format: formatAPEXAperture},
+	0x9203: {Name: "BrightnessValue", Type: typeSRational, Count: 1, Format: formatEV},
+	0x9204: {Name: "ExposureBiasValue", Type: typeSRational, Count: 1, Format: formatEV},
+	0x9205: {Name: "MaxApertureValue", Type: typeRational, Count: 1, Format: formatAPEXAperture},
+	0x9206: {Name: "SubjectDistance", Type: typeRational, Count: 1, Format: formatSubjectDistance},
+	0x9207: {Name: "MeteringMode", Type: typeShort, Count: 1, Format: formatEnum(meteringModeNames)},
+	0x9208: {Name: "LightSource", Type: typeShort, Count: 1, Format: formatEnum(lightSourceNames)},
+	0x9209: {Name: "Flash", Type: typeShort, Count: 1, Format: formatFlash},
+	0x920A: {Name: "FocalLength", Type: typeRational, Count: 1, Format: formatMillimeters},
+	0x9214: {Name: "SubjectArea", Type: typeShort, Format: formatSubjectArea},
+	0x927C: {Name: "MakerNote", Type: typeUndefined, Format: formatByteSize},
+	0x9286: {Name: "UserComment", Type: typeUndefined, Format: formatCharacterCode},
+	0x9290: {Name: "SubSecTime", Type: typeASCII},
+	0x9291: {Name: "SubSecTimeOriginal", Type: typeASCII},
+	0x9292: {Name: "SubSecTimeDigitized", Type: typeASCII},
+	0x9400: {Name: "Temperature", Type: typeSRational, Count: 1, Format: formatUnit("°C", 1)},
+	0x9401: {Name: "Humidity", Type: typeRational, Count: 1, Format: formatUnit("%", 1)},
+	0x9402: {Name: "Pressure", Type: typeRational, Count: 1, Format: formatUnit("hPa", 1)},
+	0x9403: {Name: "WaterDepth", Type: typeSRational, Count: 1, Format: formatUnit("m", 2)},
+	0x9404: {Name: "Acceleration", Type: typeRational, Count: 1, Format: formatUnit("mGal", 2)},
+	0x9405: {Name: "CameraElevationAngle", Type: typeSRational, Count: 1, Format: formatUnit("°", 2)},
+	0xA000: {Name: "FlashpixVersion", Type: typeUndefined, Count: 4, Format: formatVersion},
+	0xA001: {Name: "ColorSpace", Type: typeShort, Count: 1, Format: formatEnum(colorSpaceNames)},
+	0xA002: {Name: "PixelXDimension", Type: typeLong, Count: 1},
+	0xA003: {Name: "PixelYDimension", Type: typeLong, Count: 1},
+	0xA004: {Name: "RelatedSoundFile", Type: typeASCII, Count: 13},
+	0xA005: {Name: "InteroperabilityIFDPointer", Type: typeLong, Count: 1},
+	0xA20B: {Name: "FlashEnergy", Type: typeRational, Count: 1},
+	0xA20C: {Name: "SpatialFrequencyResponse", Type: typeUndefined, Format: formatByteSize},
+	0xA20E: {Name: "FocalPlaneXResolution", Type: typeRational, Count: 1, Format: formatResolution},
+	0xA20F: {Name: "FocalPlaneYResolution", Type: typeRational, Count: 1, Format: formatResolution},
+	0xA210: {Name: "FocalPlaneResolutionUnit", Type: typeShort, Count: 1, Format: formatEnum(focalPlaneResolutionUnitNames)},
+	0xA214: {Name: "SubjectLocation", Type: typeShort, Count: 2},
+	0xA215: {Name: "ExposureIndex", Type: typeRational, Count: 1},
+	0xA217: {Name: "SensingMethod", Type: typeShort, Count: 1, Format: formatEnum(sensingMethodNames)},
+	0xA300: {Name: "FileSource", Type: typeUndefined, Count: 1, Format: formatEnum(fileSourceNames)},
+	0xA301: {Name: "SceneType", Type: typeUndefined, Count: 1, Format: formatEnum(sceneTypeNames)},
+	0xA302: {Name: "CFAPattern", Type: typeUndefined},
+	0xA401: {Name: "CustomRendered", Type: typeShort, Count: 1, Format: formatEnum(customRenderedNames)},
+	0xA402: {Name: "ExposureMode", Type: typeShort, Count: 1, Format: formatEnum(exposureModeNames)},
+	0xA403: {Name: "WhiteBalance", Type: typeShort, Count: 1, Format: formatEnum(whiteBalanceNames)},
+	0xA404: {Name: "DigitalZoomRatio", Type: typeRational, Count: 1},
+	0xA405: {Name: "FocalLengthIn35mmFilm", Type: typeShort, Count: 1, Format: formatMillimeters},
+	0xA406: {Name: "SceneCaptureType", Type: typeShort, Count: 1, Format: formatEnum(sceneCaptureTypeNames)},
+	0xA407: {Name: "GainControl", Type: typeShort, Count: 1, Format: formatEnum(gainControlNames)},
+	0xA408: {Name: "Contrast", Type: typeShort, Count: 1, Format: formatEnum(contrastNames)},
+	0xA409: {Name: "Saturation", Type: typeShort, Count: 1, Format: formatEnum(saturationNames)},
+	0xA40A: {Name: "Sharpness", Type: typeShort, Count: 1, Format: formatEnum(sharpnessNames)},
+	0xA40B: {Name: "DeviceSettingDescription", Type: typeUndefined, Format: formatByteSize},
+	0xA40C: {Name: "SubjectDistanceRange", Type: typeShort, Count: 1, Format: formatEnum(subjectDistanceRangeNames)},
+	0xA420: {Name: "ImageUniqueID", Type: typeASCII, Count: 33},
+	0xA430: {Name: "CameraOwnerName", Type: typeASCII},
+	0xA431: {Name: "BodySerialNumber", Type: typeASCII},
+	0xA432: {Name: "LensSpecification", Type: typeRational, Count: 4, Format: formatLensSpecification},
+	0xA433: {Name: "LensMake", Type: typeASCII},
+	0xA434: {Name: "LensModel", Type: typeASCII},
+	0xA435: {Name: "LensSerialNumber", Type: typeASCII},
+	0xA436: {Name: "ImageTitle", Type: typeUTF8},
+	0xA437: {Name: "Photographer", Type: typeUTF8},
+	0xA438: {Name: "ImageEditor", Type: typeUTF8},
+	0xA439: {Name: "CameraFirmware", Type: typeUTF8},
+	0xA43A: {Name: "RAWDevelopingSoftware", Type: typeUTF8},
+	0xA43B: {Name: "ImageEditingSoftware", Type: typeUTF8},
+	0xA43C: {Name: "MetadataEditingSoftware", Type: typeUTF8},
+	0xA460: {Name: "CompositeImage", Type: typeShort, Count: 1, Format: formatEnum(compositeImageNames)},
+	0xA461: {Name: "SourceImageNumberOfCompositeImage", Type: typeShort, Count: 2},
+	0xA462: {Name: "SourceExposureTimesOfCompositeImage", Type: typeUndefined, Format: formatByteSize},
+	0xA500: {Name: "Gamma", Type: typeRational, Count: 1},
+}
+
+// gpsTags covers the GPS IFD
+var gpsTags = tagTable{
+	0x0000: {Name: "GPSVersionID", Type: typeByte, Count: 4},
+	0x0001: {Name: "GPSLatitudeRef", Type: typeASCII, Count: 2},
+	0x0002: {Name: "GPSLatitude", Type: typeRational, Count: 3},
+	0x0003: {Name: "GPSLongitudeRef", Type: typeASCII, Count: 2},
+	0x0004: {Name: "GPSLongitude", Type: typeRational, Count: 3},
+	0x0005: {Name: "GPSAltitudeRef", Type: typeByte, Count: 1},
+	0x0006: {Name: "GPSAltitude", Type: typeRational, Count: 1},
+	0x0007: {Name: "GPSTimeStamp", Type: typeRational, Count: 3},
+	0x0008: {Name: "GPSSatellites", Type: typeASCII},
+	0x0009: {Name: "GPSStatus", Type: typeASCII, Count: 2},
+	0x000A: {Name: "GPSMeasureMode", Type: typeASCII, Count: 2},
+	0x000B: {Name: "GPSDOP", Type: typeRational, Count: 1},
+	0x000C: {Name: "GPSSpeedRef", Type: typeASCII, Count: 2},
+	0x000D: {Name: "GPSSpeed", Type: typeRational, Count: 1},
+	0x000E: {Name: "GPSTrackRef", Type: typeASCII, Count: 2},
+	0x000F: {Name: "GPSTrack", Type: typeRational, Count: 1},
+	0x0010: {Name: "GPSImgDirectionRef", Type: typeASCII, Count: 2},
+	0x0011: {Name: "GPSImgDirection", Type: typeRational, Count: 1},
+	0x0012: {Name: "GPSMapDatum", Type: typeASCII},
+	0x0013: {Name: "GPSDestLatitudeRef", Type: typeASCII, Count: 2},
+	0x0014: {Name: "GPSDestLatitude", Type: typeRational, Count: 3},
+	0x0015: {Name: "GPSDestLongitudeRef", Type: typeASCII, Count: 2},
+	0x0016: {Name: "GPSDestLongitude", Type: typeRational, Count: 3},
+	0x0017: {Name: "GPSDestBearingRef", Type: typeASCII, Count: 2},
+	0x0018: {Name: "GPSDestBearing", Type: typeRational, Count: 1},
+	0x0019: {Name: "GPSDestDistanceRef", Type: typeASCII, Count: 2},
+	0x001A: {Name: "GPSDestDistance", Type: typeRational, Count: 1},
+	0x001B: {Name: "GPSProcessingMethod", Type: typeUndefined, Format: formatCharacterCode},
+	0x001C: {Name: "GPSAreaInformation", Type: typeUndefined, Format: formatCharacterCode},
+	0x001D: {Name: "GPSDateStamp", Type: typeASCII, Count: 11},
+	0x001E: {Name: "GPSDifferential", Type: typeShort, Count: 1},
+	0x001F: {Name: "GPSHPositioningError", Type: typeRational, Count: 1},
+}
+
+// interopTags covers the Interoperability IFD
+var interopTags = tagTable{
+	0x0001: {Name: "InteroperabilityIndex", Type: typeASCII, Count: 4, Format: formatInteropIndex},
+	0x0002: {Name: "InteroperabilityVersion", Type: typeUndefined, Count: 4, Format: formatVersion},
+	0x1000: {Name: "RelatedImageFileFormat", Type: typeASCII},
+	0x1001: {Name: "RelatedImageWidth", Type: typeLong, Count: 1},
+	0x1002: {Name: "RelatedImageLength", Type: typeLong, Count: 1},
+}
+
+// formatCount renders only the number of elements of an array value
+// (used for offset tables such as StripOffsets)
+func formatCount(singular, plural string) func(value interface{}) string {
+	return func(value interface{}) string {
+		n := valueLen(value)
+		if n == 1 {
+			return "1 " + singular
+		}
+		return fmt.Sprintf("%d %s", n, plural)
+	}
+}
+
+// formatByteSize renders the size of an opaque binary block
+func formatByteSize(value interface{}) string {
+	return fmt.Sprintf("(%d bytes)", valueLen(value))
+}
+
+// formatCharacterCode decodes an UNDEFINED value with an 8-byte character code prefix
+func formatCharacterCode(value interface{}) string {
+	return decodeCharacterCode(valueBytesOrText(value))
+}
+
+// formatXPString decodes the UCS-2 little-endian strings written by Windows Explorer
+func formatXPString(value interface{}) string {
+	raw, ok := value.([]uint8)
+	if !ok {
+		return formatTagValue(value)
+	}
+
+	units := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		units = append(units, uint16(raw[i])|uint16(raw[i+1])<<8)
+	}
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+}
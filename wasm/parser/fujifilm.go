@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Fujifilm MakerNote tags
+// The note starts with "FUJIFILM" and the little-endian offset of its IFD;
+// the byte order is always little-endian and offsets are relative to the
+// start of the note
+const fujiWhiteBalanceFineTune = 0x100A
+
+var fujiTags = tagTable{
+	0x0000: {Name: "Version", Type: typeUndefined, Count: 4, Format: formatVersion},
+	0x0010: {Name: "InternalSerialNumber", Type: typeASCII},
+	0x1000: {Name: "Quality", Type: typeASCII},
+	0x1001: {Name: "Sharpness", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		0: "-4 (softest)", 1: "-3 (very soft)", 2: "-2 (soft)", 3: "0 (normal)", 4: "+2 (hard)",
+		5: "+3 (very hard)", 6: "+4 (hardest)", 0x82: "-1 (medium soft)", 0x84: "+1 (medium hard)",
+		0x8000: "Film Simulation", 0xFFFF: "n/a",
+	})},
+	0x1002: {Name: "WhiteBalance", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		0x000: "Auto", 0x001: "Auto (white priority)", 0x002: "Auto (ambiance priority)",
+		0x100: "Daylight", 0x200: "Cloudy", 0x300: "Daylight Fluorescent",
+		0x301: "Day White Fluorescent", 0x302: "White Fluorescent", 0x303: "Warm White Fluorescent",
+		0x304: "Living Room Warm White Fluorescent", 0x400: "Incandescent", 0x500: "Flash",
+		0x600: "Underwater", 0xF00: "Custom", 0xF01: "Custom2", 0xF02: "Custom3",
+		0xF03: "Custom4", 0xF04: "Custom5", 0xFF0: "Kelvin",
+	})},
+	0x1003: {Name: "Saturation", Type: typeShort, Count: 1, Format: formatEnum(fujiSaturationNames)},
+	0x1005: {Name: "ColorTemperature", Type: typeShort, Count: 1, Format: formatUnit("K", 0)},
+	0x1011: {Name: "FlashExposureComp", Type: typeSRational, Count: 1, Format: formatEV},
+	0x1020: {Name: "Macro", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{0: "Off", 1: "On"})},
+	0x1021: {Name: "FocusMode", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{0: "Auto", 1: "Manual", 65535: "Movie"})},
+	0x1040: {Name: "ShadowTone", Type: typeSLong, Count: 1, Format: formatFujiTone},
+	0x1041: {Name: "HighlightTone", Type: typeSLong, Count: 1, Format: formatFujiTone},
+	0x1047: {Name: "GrainEffectRoughness", Type: typeLong, Count: 1, Format: formatEnum(fujiEffectNames)},
+	0x1048: {Name: "ColorChromeEffect", Type: typeLong, Count: 1, Format: formatEnum(fujiEffectNames)},
+	0x104E: {Name: "ColorChromeFXBlue", Type: typeLong, Count: 1, Format: formatEnum(fujiEffectNames)},
+	0x1050: {Name: "ShutterType", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		0: "Mechanical", 1: "Electronic", 2: "Electronic (long shutter speed)", 3: "Electronic Front Curtain",
+	})},
+	0x1101: {Name: "SequenceNumber", Type: typeShort, Count: 1},
+	0x1400: {Name: "DynamicRange", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{1: "Standard", 3: "Wide"})},
+	0x1401: {Name: "FilmMode", Type: typeShort, Count: 1, Format: formatEnum(fujiFilmModeNames)},
+	0x1402: {Name: "DynamicRangeSetting", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		0x0000: "Auto", 0x0001: "Manual", 0x0100: "Standard (100%)", 0x0200: "Wide1 (230%)",
+		0x0201: "Wide2 (400%)", 0x8000: "Film Simulation",
+	})},
+	0x1403: {Name: "DevelopmentDynamicRange", Type: typeShort, Count: 1, Format: func(value interface{}) string {
+		v, ok := firstInt(value)
+		if !ok || v == 0 {
+			return ""
+		}
+		return fmt.Sprintf("DR%d", v)
+	}},
+	0x1404: {Name: "MinFocalLength", Type: typeRational, Count: 1, Format: formatMillimeters},
+	0x1405: {Name: "MaxFocalLength", Type: typeRational, Count: 1, Format: formatMillimeters},
+	0x1406: {Name: "MaxApertureAtMinFocal", Type: typeRational, Count: 1, Format: formatFNumber},
+	0x1407: {Name: "MaxApertureAtMaxFocal", Type: typeRational, Count: 1, Format: formatFNumber},
+	0x1438: {Name: "ImageCount", Type: typeShort, Count: 1, Format: func(value interface{}) string {
+		v, _ := firstInt(value)
+		return fmt.Sprintf("%d", v&0x7FFF)
+	}},
+	0x1443: {Name: "DRangePriority", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{0: "Auto", 1: "Fixed"})},
+}
+
+// fujiFilmModeNames are the color film simulations (FilmMode 0x1401)
+var fujiFilmModeNames = map[int]string{
+	0x000: "F0/Standard (Provia)", 0x100: "F1/Studio Portrait",
+	0x110: "F1a/Studio Portrait Enhanced Saturation", 0x120: "F1b/Studio Portrait Smooth Skin Tone (Astia)",
+	0x130: "F1c/Studio Portrait Increased Sharpness", 0x200: "F2/Fujichrome (Velvia)",
+	0x300: "F3/Studio Portrait Ex", 0x400: "F4/Velvia", 0x500: "Pro Neg. Std", 0x501: "Pro Neg. Hi",
+	0x600: "Classic Chrome", 0x700: "Eterna", 0x800: "Classic Negative", 0x900: "Bleach Bypass",
+	0xA00: "Nostalgic Neg", 0xB00: "Reala ACE",
+}
+
+// fujiSaturationNames also carries the monochrome film simulations,
+// which are stored in Saturation rather than FilmMode
+var fujiSaturationNames = map[int]string{
+	0x000: "0 (normal)", 0x080: "+1 (medium high)", 0x100: "+2 (high)", 0x0C0: "+3 (very high)",
+	0x0E0: "+4 (highest)", 0x180: "-1 (medium low)", 0x200: "Low", 0x400: "-3 (very low)",
+	0x4C0: "-4 (lowest)", 0x300: "Black & White", 0x301: "B&W Red Filter",
+	0x302: "B&W Yellow Filter", 0x303: "B&W Green Filter", 0x310: "B&W Sepia", 0x500: "Acros",
+	0x501: "Acros Red Filter", 0x502: "Acros Yellow Filter", 0x503: "Acros Green Filter",
+	0x8000: "Film Simulation",
+}
+
+var fujiEffectNames = map[int]string{0: "Off", 32: "Weak", 64: "Strong"}
+
+// parseFujifilmMakerNote decodes a Fujifilm MakerNote
+func parseFujifilmMakerNote(p *SimpleExifParser, r *tiffReader, offset, size int) {
+	note := r.data[offset : offset+size]
+	if len(note) < 12 || string(note[:8]) != "FUJIFILM" {
+		return
+	}
+
+	fr := r.embedded(offset, offset+size, binary.LittleEndian)
+	p.parseMakerNoteIFD(fr, int(binary.LittleEndian.Uint32(note[8:12])), GroupFujifilm, fujiTags, func(tag uint16, raw interface{}, entryOffset int) bool {
+		if tag != fujiWhiteBalanceFineTune {
+			return false
+		}
+		// Stored in 1/20 steps of the red and blue shift
+		values := valueFloats(raw)
+		if len(values) >= 2 {
+			value := fmt.Sprintf("Red %+d, Blue %+d", int(values[0])/20, int(values[1])/20)
+			fr.set(GroupFujifilm, tag, "WhiteBalanceFineTune", raw, value, entryOffset)
+		}
+		return true
+	})
+
+	// FilmSimulation combines FilmMode with the monochrome simulations
+	filmMode := fr.md.Get(GroupFujifilm, "FilmMode")
+	if saturation := fr.md.Get(GroupFujifilm, "Saturation"); saturation != nil {
+		if v, ok := firstInt(saturation.Raw); ok && (v&0xF00 == 0x300 || v&0xF00 == 0x500) {
+			fr.md.Set(GroupFujifilm, "FilmSimulation", saturation.Value, saturation.Offset, fr.source)
+			return
+		}
+	}
+	if filmMode != nil {
+		fr.md.Set(GroupFujifilm, "FilmSimulation", filmMode.Value, filmMode.Offset, fr.source)
+	}
+}
+
+// formatFujiTone renders ShadowTone/HighlightTone, stored in steps of -16
+func formatFujiTone(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok {
+		return ""
+	}
+	tone := float64(-v) / 16
+	if tone > 0 {
+		return "+" + formatDecimal(tone, 1)
+	}
+	return formatDecimal(tone, 1)
+}
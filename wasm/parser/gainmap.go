@@ -0,0 +1,189 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// HDR gain map JPEGs (Android Ultra HDR, Apple HDR photos, ISO 21496-1)
+// store an SDR primary image followed by a gain map JPEG, located through
+// MPF or Container:Directory. HDR displays apply the gain map to the
+// primary image; other viewers show the primary image alone.
+
+// isoGainMapSignature starts the APP2 segments carrying ISO 21496-1 gain
+// map metadata: a version in the primary image, the parameters in the
+// gain map image
+const isoGainMapSignature = "urn:iso:std:iso:ts:21496:-1\x00"
+
+// hdrgmProperties lists the Ultra HDR parameters (hdrgm namespace) in the
+// order they are reported. Per-channel values are stored as rdf:Seq
+var hdrgmProperties = []string{
+	"Version",
+	"BaseRenditionIsHDR",
+	"GainMapMin",
+	"GainMapMax",
+	"Gamma",
+	"OffsetSDR",
+	"OffsetHDR",
+	"HDRCapacityMin",
+	"HDRCapacityMax",
+}
+
+// GainMap is the gain map image of an HDR gain map JPEG
+type GainMap struct {
+	// Offset is the absolute file offset of the gain map JPEG
+	Offset int
+
+	// Data is the complete gain map JPEG
+	Data []byte
+}
+
+// ErrNoGainMap is returned when the image has no HDR gain map
+var ErrNoGainMap = errors.New("no HDR gain map found")
+
+// GainMap returns the gain map found while parsing, or nil
+func (m *Metadata) GainMap() *GainMap {
+	return m.gainMap
+}
+
+// ExtractGainMap parses the image and returns its HDR gain map JPEG
+func ExtractGainMap(data []byte) (*GainMap, error) {
+	md, err := ParseImage(data)
+	if err != nil {
+		return nil, err
+	}
+	if md.gainMap == nil {
+		return nil, ErrNoGainMap
+	}
+	return md.gainMap, nil
+}
+
+// reportISOGainMap reports an ISO 21496-1 APP2 segment: the minimum and
+// writer versions, followed by the parameters in the gain map image
+func reportISOGainMap(md *Metadata, data []byte, offset int, source string) {
+	c := &byteCursor{data: data}
+	minimum, writer := c.u16(), c.u16()
+	if c.err != nil {
+		return
+	}
+	md.Set(GroupGainMap, "ISOGainMapVersion", fmt.Sprintf("%d (writer %d, %d bytes)", minimum, writer, len(data)), offset, source)
+}
+
+// reportGainMap locates the gain map image of a JPEG and reports its
+// format, parameters and location. MPF images are checked first, then the
+// Container:Directory item with the "GainMap" semantic. end is the offset
+// just past the primary image's EOI
+func reportGainMap(md *Metadata, file []byte, end int) {
+	var gainMap *GainMap
+	var gainMapMD *Metadata
+	source := ""
+
+	for i := range md.mpImages {
+		img := &md.mpImages[i]
+		if img.imageType == mpTypeGainMap || isGainMapImage(img.md) {
+			gainMap = &GainMap{Offset: img.offset, Data: img.data}
+			gainMapMD = img.md
+			source = "MPF"
+			break
+		}
+	}
+	if gainMap == nil {
+		items := readContainerDirectory(md)
+		locateContainerItems(items, end, len(file))
+		if item := findContainerItem(items, "GainMap"); item != nil && item.Length <= len(file)-item.Offset {
+			data := file[item.Offset : item.Offset+item.Length]
+			if len(data) >= 2 && data[0] == 0xFF && data[1] == 0xD8 {
+				gainMap = &GainMap{Offset: item.Offset, Data: data}
+				gainMapMD, _ = (&SimpleExifParser{nested: true}).Parse(data)
+				source = "XMP"
+			} else {
+				md.Set(GroupErrors, "GainMap_ParseError", "Container:Directory gain map is not a JPEG", item.Offset, "XMP")
+			}
+		}
+	}
+
+	// Ultra HDR writes the version in the primary image and the
+	// parameters in the gain map image; early files have both in the first
+	format := ""
+	switch {
+	case xmpValue(md, "hdrgm:Version") != "" || (gainMapMD != nil && xmpValue(gainMapMD, "hdrgm:Version") != ""):
+		format = "Ultra HDR"
+	case gainMapMD != nil && xmpValue(gainMapMD, "HDRGainMap:HDRGainMapVersion") != "":
+		format = "Apple HDR gain map"
+	case md.Get(GroupGainMap, "ISOGainMapVersion") != nil:
+		format = "ISO 21496-1"
+	case gainMap != nil:
+		format = "Gain map"
+	default:
+		return
+	}
+	if md.Get(GroupGainMap, "ISOGainMapVersion") != nil && format != "ISO 21496-1" {
+		format += ", ISO 21496-1"
+	}
+	md.Set(GroupGainMap, "HDRFormat", format, -1, source)
+
+	for _, name := range hdrgmProperties {
+		value := ""
+		if gainMapMD != nil {
+			value = xmpValue(gainMapMD, "hdrgm:"+name)
+		}
+		if value == "" {
+			value = xmpValue(md, "hdrgm:"+name)
+		}
+		if value == "" {
+			continue
+		}
+		if name == "HDRCapacityMin" || name == "HDRCapacityMax" {
+			// Capacities are log2 of the display headroom
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				value += fmt.Sprintf(" (%sx)", formatDecimal(math.Pow(2, v), 2))
+			}
+		}
+		md.Set(GroupGainMap, name, value, -1, "XMP")
+	}
+	if gainMapMD != nil {
+		if version := xmpValue(gainMapMD, "HDRGainMap:HDRGainMapVersion"); version != "" {
+			md.Set(GroupGainMap, "HDRGainMapVersion", version, -1, "XMP")
+		}
+	}
+
+	if gainMap == nil {
+		md.Set(GroupGainMap, "GainMapImage", "not found", -1, "")
+		return
+	}
+	summary := fmt.Sprintf("JPEG, %d bytes", len(gainMap.Data))
+	if gainMapMD != nil {
+		if dims := frameSize(gainMapMD); dims != "" {
+			summary = fmt.Sprintf("JPEG %s, %d bytes", dims, len(gainMap.Data))
+		}
+	}
+	md.Set(GroupGainMap, "GainMapImage", summary, gainMap.Offset, source)
+	md.gainMap = gainMap
+}
+
+// isGainMapImage reports whether an embedded image declares itself a
+// gain map in its own XMP or ISO 21496-1 segment
+func isGainMapImage(md *Metadata) bool {
+	return xmpValue(md, "hdrgm:Version") != "" ||
+		xmpValue(md, "HDRGainMap:HDRGainMapVersion") != "" ||
+		md.Get(GroupGainMap, "ISOGainMapVersion") != nil
+}
+
+// xmpValue returns an XMP property, joining the items of an array
+func xmpValue(md *Metadata, path string) string {
+	if e := md.Get(GroupXMP, path); e != nil {
+		return e.Value
+	}
+	var values []string
+	for i := 1; ; i++ {
+		e := md.Get(GroupXMP, fmt.Sprintf("%s[%d]", path, i))
+		if e == nil {
+			break
+		}
+		values = append(values, e.Value)
+	}
+	return strings.Join(values, ", ")
+}
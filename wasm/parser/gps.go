@@ -0,0 +1,284 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// gpsEntry is a decoded GPS IFD entry awaiting interpretation
+type gpsEntry struct {
+	value  interface{}
+	offset int
+}
+
+// parseGPSIFD parses the GPS sub-IFD and stores decoded location data
+// All entries are read first so that reference tags (N/S, E/W,
+// above/below sea level, units) can be applied to their values
+func (p *SimpleExifParser) parseGPSIFD(r *tiffReader, offset int) {
+	data, byteOrder := r.data, r.byteOrder
+	if offset < 0 || offset+2 > len(data) {
+		return
+	}
+
+	numEntries := byteOrder.Uint16(data[offset : offset+2])
+	offset += 2
+
+	entries := make(map[uint16]gpsEntry)
+	var order []uint16
+
+	for i := 0; i < int(numEntries); i++ {
+		entryOffset := offset + i*12
+		if entryOffset+12 > len(data) {
+			break
+		}
+
+		tag := byteOrder.Uint16(data[entryOffset : entryOffset+2])
+		dataType := byteOrder.Uint16(data[entryOffset+2 : entryOffset+4])
+		count := byteOrder.Uint32(data[entryOffset+4 : entryOffset+8])
+
+		value, ok := readTagValue(dataType, count, entryOffset+8, data, byteOrder)
+		if !ok {
+			continue
+		}
+		r.checkFormat(GroupGPS, lookupTag(ifdGPS, tag), dataType, count, entryOffset)
+		if _, seen := entries[tag]; !seen {
+			order = append(order, tag)
+		}
+		entries[tag] = gpsEntry{value: value, offset: entryOffset}
+	}
+
+	ref := func(tag uint16) string {
+		return valueString(entries[tag].value)
+	}
+
+	for _, tag := range order {
+		entry := entries[tag]
+		value := entry.value
+		var text string
+
+		switch tag {
+		case tagGPSVersionID:
+			version := valueFloats(value)
+			parts := make([]string, len(version))
+			for i, v := range version {
+				parts[i] = fmt.Sprintf("%d", int(v))
+			}
+			text = strings.Join(parts, ".")
+
+		case tagGPSLatitude:
+			if lat, ok := gpsDecimalDegrees(valueFloats(value), ref(tagGPSLatitudeRef), "S"); ok {
+				text = fmt.Sprintf("%.6f", lat)
+			}
+		case tagGPSLongitude:
+			if lon, ok := gpsDecimalDegrees(valueFloats(value), ref(tagGPSLongitudeRef), "W"); ok {
+				text = fmt.Sprintf("%.6f", lon)
+			}
+		case tagGPSDestLatitude:
+			if lat, ok := gpsDecimalDegrees(valueFloats(value), ref(tagGPSDestLatitudeRef), "S"); ok {
+				text = fmt.Sprintf("%.6f", lat)
+			}
+		case tagGPSDestLongitude:
+			if lon, ok := gpsDecimalDegrees(valueFloats(value), ref(tagGPSDestLongitudeRef), "W"); ok {
+				text = fmt.Sprintf("%.6f", lon)
+			}
+
+		case tagGPSAltitude:
+			if v := valueFloats(value); len(v) > 0 {
+				altitude := v[0]
+				if altitudeRef := valueFloats(entries[tagGPSAltitudeRef].value); len(altitudeRef) > 0 && altitudeRef[0] == 1 {
+					// 1 = below sea level
+					altitude = -altitude
+				}
+				text = fmt.Sprintf("%.1f m", altitude)
+			}
+
+		case tagGPSTimeStamp:
+			if v := valueFloats(value); len(v) >= 3 {
+				text = gpsFormatTime(v)
+			}
+		case tagGPSDateStamp:
+			text = valueString(value)
+
+		case tagGPSSatellites, tagGPSMapDatum:
+			text = valueString(value)
+		case tagGPSStatus:
+			switch valueString(value) {
+			case "A":
+				text = "Measurement in progress"
+			case "V":
+				text = "Measurement interrupted"
+			}
+		case tagGPSMeasureMode:
+			switch valueString(value) {
+			case "2":
+				text = "2-dimensional"
+			case "3":
+				text = "3-dimensional"
+			}
+		case tagGPSDOP:
+			if v := valueFloats(value); len(v) > 0 {
+				text = fmt.Sprintf("%.2f", v[0])
+			}
+		case tagGPSProcessingMethod, tagGPSAreaInformation:
+			text = decodeCharacterCode(valueBytesOrText(value))
+		case tagGPSDifferential:
+			if v := valueFloats(value); len(v) > 0 {
+				if v[0] == 1 {
+					text = "Differential corrected"
+				} else {
+					text = "No correction"
+				}
+			}
+		case tagGPSHPositioningError:
+			if v := valueFloats(value); len(v) > 0 {
+				text = fmt.Sprintf("%.1f m", v[0])
+			}
+
+		case tagGPSSpeed:
+			unit := "km/h"
+			switch ref(tagGPSSpeedRef) {
+			case "M":
+				unit = "mph"
+			case "N":
+				unit = "knots"
+			}
+			text = gpsFormatNumber(value, unit)
+		case tagGPSDestDistance:
+			unit := "km"
+			switch ref(tagGPSDestDistanceRef) {
+			case "M":
+				unit = "miles"
+			case "N":
+				unit = "nautical miles"
+			}
+			text = gpsFormatNumber(value, unit)
+		case tagGPSTrack:
+			text = gpsFormatDirection(value, ref(tagGPSTrackRef))
+		case tagGPSImgDirection:
+			text = gpsFormatDirection(value, ref(tagGPSImgDirectionRef))
+		case tagGPSDestBearing:
+			text = gpsFormatDirection(value, ref(tagGPSDestBearingRef))
+
+		case tagGPSLatitudeRef, tagGPSLongitudeRef, tagGPSDestLatitudeRef, tagGPSDestLongitudeRef,
+			tagGPSAltitudeRef, tagGPSSpeedRef, tagGPSTrackRef, tagGPSImgDirectionRef,
+			tagGPSDestBearingRef, tagGPSDestDistanceRef:
+			// Applied to the values they qualify
+			continue
+
+		default:
+			// Tags outside the GPS tag set are reported with their raw value
+			text = formatTagValue(value)
+		}
+
+		if text == "" {
+			continue
+		}
+		r.set(GroupGPS, tag, lookupTag(ifdGPS, tag).Name, value, text, entry.offset)
+	}
+
+	// Combined UTC timestamp
+	date, hasDate := entries[tagGPSDateStamp]
+	timeEntry, hasTime := entries[tagGPSTimeStamp]
+	if hasDate && hasTime {
+		if hms := valueFloats(timeEntry.value); len(hms) >= 3 {
+			r.md.Group(GroupGPS).Set(&Entry{
+				Name:   "GPSDateTime",
+				Value:  valueString(date.value) + " " + gpsFormatTime(hms) + " UTC",
+				Offset: r.base + date.offset,
+				Source: r.source,
+			})
+		}
+	}
+}
+
+// gpsDecimalDegrees converts degrees/minutes/seconds to signed decimal degrees
+// The value is negated when ref equals negativeRef ("S" or "W")
+func gpsDecimalDegrees(dms []float64, ref string, negativeRef string) (float64, bool) {
+	if len(dms) == 0 {
+		return 0, false
+	}
+
+	degrees := dms[0]
+	if len(dms) > 1 {
+		degrees += dms[1] / 60
+	}
+	if len(dms) > 2 {
+		degrees += dms[2] / 3600
+	}
+
+	if math.IsNaN(degrees) || math.IsInf(degrees, 0) {
+		return 0, false
+	}
+	if strings.EqualFold(ref, negativeRef) {
+		degrees = -degrees
+	}
+	return degrees, true
+}
+
+// gpsFormatTime formats an hour/minute/second triple as HH:MM:SS
+func gpsFormatTime(hms []float64) string {
+	hour, minute, second := int(hms[0]), int(hms[1]), hms[2]
+	if second == math.Trunc(second) {
+		return fmt.Sprintf("%02d:%02d:%02d", hour, minute, int(second))
+	}
+	return fmt.Sprintf("%02d:%02d:%06.3f", hour, minute, second)
+}
+
+// gpsFormatNumber renders the first value of a rational with its unit
+func gpsFormatNumber(value interface{}, unit string) string {
+	v := valueFloats(value)
+	if len(v) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%g %s", v[0], unit)
+}
+
+// gpsFormatDirection renders a direction in degrees with its reference north
+func gpsFormatDirection(value interface{}, ref string) string {
+	v := valueFloats(value)
+	if len(v) == 0 {
+		return ""
+	}
+	text := fmt.Sprintf("%g°", v[0])
+	switch ref {
+	case "T":
+		return text + " (True North)"
+	case "M":
+		return text + " (Magnetic North)"
+	}
+	return text
+}
+
+// valueBytesOrText returns the bytes of an UNDEFINED or ASCII value
+func valueBytesOrText(value interface{}) []byte {
+	switch v := value.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	}
+	return nil
+}
+
+// decodeCharacterCode decodes an UNDEFINED value prefixed with an
+// 8-byte character code (used by UserComment and GPSProcessingMethod)
+// "ASCII\x00\x00\x00" = ASCII
+// "JIS\x00\x00\x00\x00\x00" = JIS
+// "UNICODE\x00" = Unicode
+// "\x00\x00\x00\x00\x00\x00\x00\x00" = Undefined
+func decodeCharacterCode(data []byte) string {
+	if len(data) <= 8 {
+		return string(bytes.TrimRight(data, "\x00 "))
+	}
+
+	charset := string(bytes.TrimRight(data[0:8], "\x00"))
+	value := string(bytes.TrimRight(data[8:], "\x00"))
+
+	// Add charset info if not ASCII or undefined
+	if charset != "" && charset != "ASCII" {
+		value = fmt.Sprintf("%s (charset: %s)", value, charset)
+	}
+	return value
+}
@@ -1,15 +1,746 @@
 package parser
 
-import "fmt"
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
 
-// HEIFParser handles HEIF/HEIC format images
-// Currently a stub for future implementation
-type HEIFParser struct{}
+// HEIFParser handles HEIF/HEIC format images (and AVIF through AVIFParser)
+// Walks the ISOBMFF box structure (ftyp, meta/iinf/iloc/iprp) to locate
+// the Exif and XMP items and the properties of the primary image
+type HEIFParser struct {
+	exifParser *SimpleExifParser
+}
+
+// heifExtent is one contiguous piece of an item's data
+type heifExtent struct {
+	Offset, Length uint64
+}
+
+// heifItem is an entry of the item information box (infe) with its
+// location (iloc) and associated properties (ipma)
+type heifItem struct {
+	ID          uint32
+	Type        string
+	Name        string
+	ContentType string
+
+	ConstructionMethod int
+	BaseOffset         uint64
+	Extents            []heifExtent
+
+	// Properties are 1-based indices into heifFile.properties
+	Properties []int
+}
+
+// heifFile is the decoded 'meta' box of a HEIF/AVIF file
+type heifFile struct {
+	data []byte
+
+	majorBrand       string
+	minorVersion     uint32
+	compatibleBrands []string
+
+	handler    string
+	primaryID  uint32
+	items      map[uint32]*heifItem
+	itemOrder  []uint32
+	properties []isoBox
+	idat       *isoBox
+
+	// boxes are the top-level boxes of the file
+	boxes []isoBox
+
+	// errors lists the meta boxes that could not be fully decoded; what
+	// was read before each failure is kept
+	errors []string
+}
 
 // Parse extracts EXIF/metadata from HEIF images
-// TODO: Implement HEIF container parsing for EXIF data
-func (p *HEIFParser) Parse(data []byte) (ExifData, error) {
-	return nil, fmt.Errorf("HEIF format not yet implemented")
+func (p *HEIFParser) Parse(data []byte) (*Metadata, error) {
+	md := NewMetadata(FormatHEIF)
+	if err := p.parseContainer(data, md, GroupHEIF); err != nil {
+		return nil, err
+	}
+
+	if md.Len() == 0 {
+		return nil, fmt.Errorf("no metadata found in HEIF file")
+	}
+
+	return md, nil
+}
+
+// parseContainer decodes an ISOBMFF image file (HEIF or AVIF) into md
+// Container-level information is reported in the given group
+func (p *HEIFParser) parseContainer(data []byte, md *Metadata, group string) error {
+	// Initialize embedded parser
+	if p.exifParser == nil {
+		p.exifParser = &SimpleExifParser{}
+	}
+
+	f, err := readHEIFFile(data)
+	if err != nil {
+		return err
+	}
+
+	if len(f.errors) > 0 {
+		md.Set(GroupErrors, group+"_ParseError", strings.Join(f.errors, "; "), -1, "meta")
+	}
+
+	p.reportBrands(f, md, group)
+
+	if f.handler != "" {
+		md.Set(group, "Handler", f.handler, -1, "hdlr")
+	}
+	if len(f.items) > 0 {
+		md.SetRaw(group, "ItemCount", len(f.items), fmt.Sprintf("%d", len(f.items)), -1, "iinf")
+	}
+
+	if primary, ok := f.items[f.primaryID]; ok {
+		md.SetRaw(group, "PrimaryItemID", primary.ID, fmt.Sprintf("%d", primary.ID), -1, "pitm")
+		md.Set(group, "PrimaryItemType", primary.Type, -1, "infe")
+		p.reportProperties(f, primary, md, group)
+	}
+	p.reportAlphaItems(f, md, group)
+
+	for _, id := range f.itemOrder {
+		item := f.items[id]
+
+		switch {
+		case item.Type == "Exif":
+			p.parseExifItem(f, item, md)
+
+		case item.Type == "mime" && isXMPContentType(item.ContentType):
+			if itemData, offset, err := f.itemData(item); err == nil {
+				reportXMP(md, itemData, offset, "mime item")
+			}
+		}
+	}
+
+	for _, box := range f.boxes {
+		if box.Type == "uuid" && bytes.Equal(box.UserType, c2paBoxUUID) {
+			reportC2PABox(md, data, box)
+		}
+	}
+
+	return nil
+}
+
+// isXMPContentType reports whether a mime item holds an XMP packet
+func isXMPContentType(contentType string) bool {
+	return contentType == "application/rdf+xml" || strings.HasPrefix(contentType, "application/rdf+xml;")
+}
+
+// parseExifItem feeds an Exif item to the TIFF parser
+// The item starts with a 4-byte offset to the TIFF header
+// (usually 6, skipping an "Exif\0\0" prefix)
+func (p *HEIFParser) parseExifItem(f *heifFile, item *heifItem, md *Metadata) {
+	itemData, offset, err := f.itemData(item)
+	if err != nil {
+		md.Set(GroupErrors, "EXIF_ParseError", err.Error(), -1, "Exif item")
+		return
+	}
+	if len(itemData) < 4 {
+		md.Set(GroupErrors, "EXIF_ParseError", "Exif item too short", offset, "Exif item")
+		return
+	}
+
+	headerOffset := uint64(binary.BigEndian.Uint32(itemData[0:4]))
+	if 4+headerOffset > uint64(len(itemData)) {
+		md.Set(GroupErrors, "EXIF_ParseError", "invalid TIFF header offset in Exif item", offset, "Exif item")
+		return
+	}
+
+	start := 4 + int(headerOffset)
+	if err := p.exifParser.ParseTIFF(itemData[start:], offset+start, "Exif item", md); err != nil {
+		md.Set(GroupErrors, "EXIF_ParseError", err.Error(), offset, "Exif item")
+	}
+}
+
+// reportBrands stores the ftyp brands
+func (p *HEIFParser) reportBrands(f *heifFile, md *Metadata, group string) {
+	if f.majorBrand == "" {
+		return
+	}
+	md.Set(group, "MajorBrand", f.majorBrand, 8, "ftyp")
+	md.SetRaw(group, "MinorVersion", f.minorVersion, fmt.Sprintf("%d", f.minorVersion), 12, "ftyp")
+	if len(f.compatibleBrands) > 0 {
+		md.Set(group, "CompatibleBrands", strings.Join(f.compatibleBrands, ", "), 16, "ftyp")
+	}
+}
+
+// reportProperties stores the properties associated with the primary item
+func (p *HEIFParser) reportProperties(f *heifFile, item *heifItem, md *Metadata, group string) {
+	for _, index := range item.Properties {
+		if index < 1 || index > len(f.properties) {
+			continue
+		}
+		prop := f.properties[index-1]
+		c := &byteCursor{data: prop.Payload}
+
+		switch prop.Type {
+		case "ispe":
+			// Full box: version/flags, then 32-bit width and height
+			c.skip(4)
+			width, height := c.u32(), c.u32()
+			if c.err == nil {
+				md.SetRaw(group, "ImageWidth", width, fmt.Sprintf("%d", width), prop.Offset, "ispe")
+				md.SetRaw(group, "ImageHeight", height, fmt.Sprintf("%d", height), prop.Offset, "ispe")
+			}
+
+		case "irot":
+			// Anti-clockwise rotation in units of 90 degrees
+			angle := c.u8() & 0x03
+			if c.err == nil {
+				md.SetRaw(group, "Rotation", angle, fmt.Sprintf("%d° counter-clockwise", int(angle)*90), prop.Offset, "irot")
+			}
+
+		case "imir":
+			axis := c.u8() & 0x01
+			if c.err == nil {
+				mirror := "Vertical axis (left-right flip)"
+				if axis == 1 {
+					mirror = "Horizontal axis (top-bottom flip)"
+				}
+				md.SetRaw(group, "Mirror", axis, mirror, prop.Offset, "imir")
+			}
+
+		case "colr":
+			p.reportColour(prop, md, group)
+
+		case "pixi":
+			// Full box: num_channels, then bits per channel
+			c.skip(4)
+			channels := int(c.u8())
+			bits := make([]string, 0, channels)
+			for i := 0; i < channels && c.err == nil; i++ {
+				bits = append(bits, fmt.Sprintf("%d", c.u8()))
+			}
+			if c.err == nil && channels > 0 {
+				md.Set(group, "BitsPerChannel", strings.Join(bits, ", "), prop.Offset, "pixi")
+			}
+
+		case "av1C":
+			p.reportAV1Config(prop, md, group)
+
+		case "clli":
+			maxCLL, maxFALL := c.u16(), c.u16()
+			if c.err == nil {
+				md.SetRaw(group, "MaxContentLightLevel", maxCLL, fmt.Sprintf("%d cd/m²", maxCLL), prop.Offset, "clli")
+				md.SetRaw(group, "MaxFrameAverageLightLevel", maxFALL, fmt.Sprintf("%d cd/m²", maxFALL), prop.Offset, "clli")
+			}
+
+		case "mdcv":
+			p.reportMasteringDisplay(prop, md, group)
+		}
+	}
+}
+
+// reportAV1Config stores the AV1 codec configuration (av1C)
+func (p *HEIFParser) reportAV1Config(prop isoBox, md *Metadata, group string) {
+	if len(prop.Payload) < 3 {
+		return
+	}
+
+	profile := prop.Payload[1] >> 5
+	level := prop.Payload[1] & 0x1F
+	flags := prop.Payload[2]
+	tier := flags >> 7
+	highBitDepth := flags&0x40 != 0
+	twelveBit := flags&0x20 != 0
+	monochrome := flags&0x10 != 0
+	subsamplingX := flags&0x08 != 0
+	subsamplingY := flags&0x04 != 0
+
+	bitDepth := 8
+	if highBitDepth {
+		bitDepth = 10
+		if twelveBit && profile == 2 {
+			bitDepth = 12
+		}
+	}
+
+	var chroma string
+	switch {
+	case monochrome:
+		chroma = "4:0:0 (monochrome)"
+	case subsamplingX && subsamplingY:
+		chroma = "4:2:0"
+	case subsamplingX:
+		chroma = "4:2:2"
+	default:
+		chroma = "4:4:4"
+	}
+
+	tierName := "Main"
+	if tier == 1 {
+		tierName = "High"
+	}
+
+	md.SetRaw(group, "AV1Profile", profile, lookupCode(av1ProfileNames, int(profile)), prop.Offset, "av1C")
+	md.SetRaw(group, "AV1Level", level, fmt.Sprintf("%d (%s tier)", level, tierName), prop.Offset, "av1C")
+	md.SetRaw(group, "BitDepth", bitDepth, fmt.Sprintf("%d", bitDepth), prop.Offset, "av1C")
+	md.Set(group, "ChromaSubsampling", chroma, prop.Offset, "av1C")
+}
+
+// av1ProfileNames are the AV1 seq_profile values
+var av1ProfileNames = map[int]string{
+	0: "Main",
+	1: "High",
+	2: "Professional",
+}
+
+// reportMasteringDisplay stores the mastering display colour volume (mdcv)
+// Primaries are stored G, B, R in units of 0.00002 and luminance in 0.0001 cd/m²
+func (p *HEIFParser) reportMasteringDisplay(prop isoBox, md *Metadata, group string) {
+	c := &byteCursor{data: prop.Payload}
+
+	var primaries [3][2]float64
+	for i := range primaries {
+		primaries[i][0] = float64(c.u16()) * 0.00002
+		primaries[i][1] = float64(c.u16()) * 0.00002
+	}
+	whiteX, whiteY := float64(c.u16())*0.00002, float64(c.u16())*0.00002
+	maxLuminance := float64(c.u32()) * 0.0001
+	minLuminance := float64(c.u32()) * 0.0001
+	if c.err != nil {
+		return
+	}
+
+	green, blue, red := primaries[0], primaries[1], primaries[2]
+	md.Set(group, "MasteringDisplayPrimaries", fmt.Sprintf("R(%.4f, %.4f) G(%.4f, %.4f) B(%.4f, %.4f)",
+		red[0], red[1], green[0], green[1], blue[0], blue[1]), prop.Offset, "mdcv")
+	md.Set(group, "MasteringDisplayWhitePoint", fmt.Sprintf("(%.4f, %.4f)", whiteX, whiteY), prop.Offset, "mdcv")
+	md.Set(group, "MasteringDisplayLuminance", fmt.Sprintf("%s - %s cd/m²",
+		formatDecimal(minLuminance, 4), formatDecimal(maxLuminance, 4)), prop.Offset, "mdcv")
+}
+
+// alphaAuxTypes are the auxC URNs that mark an item as an alpha plane
+var alphaAuxTypes = map[string]bool{
+	"urn:mpeg:mpegB:cicp:systems:auxiliary:alpha": true, // AVIF
+	"urn:mpeg:hevc:2015:auxid:1":                  true, // HEIF
+}
+
+// appleAuxTypes are the auxC URNs of the auxiliary images iPhones attach
+// to HEIC captures (gain maps and Portrait mode mattes)
+var appleAuxTypes = map[string]string{
+	"urn:com:apple:photo:2020:aux:hdrgainmap":           "HDR gain map",
+	"urn:com:apple:photo:2018:aux:portraiteffectsmatte": "Portrait effects matte",
+	"urn:com:apple:photo:2019:aux:semanticskinmatte":    "Skin matte",
+	"urn:com:apple:photo:2019:aux:semantichairmatte":    "Hair matte",
+	"urn:com:apple:photo:2019:aux:semanticteethmatte":   "Teeth matte",
+}
+
+// reportAlphaItems stores the auxiliary alpha images of the file, and
+// the Apple auxiliary images of iPhone HEICs under the Apple group
+func (p *HEIFParser) reportAlphaItems(f *heifFile, md *Metadata, group string) {
+	var alpha, apple []string
+	for _, id := range f.itemOrder {
+		item := f.items[id]
+
+		auxType := ""
+		size := ""
+		for _, index := range item.Properties {
+			if index < 1 || index > len(f.properties) {
+				continue
+			}
+			prop := f.properties[index-1]
+			c := &byteCursor{data: prop.Payload}
+			switch prop.Type {
+			case "auxC":
+				c.skip(4)
+				auxType = c.cString()
+			case "ispe":
+				c.skip(4)
+				width, height := c.u32(), c.u32()
+				if c.err == nil {
+					size = fmt.Sprintf(" %dx%d", width, height)
+				}
+			}
+		}
+
+		if alphaAuxTypes[auxType] {
+			alpha = append(alpha, fmt.Sprintf("item %d (%s%s)", item.ID, item.Type, size))
+		} else if name, ok := appleAuxTypes[auxType]; ok {
+			apple = append(apple, fmt.Sprintf("%s (item %d%s)", name, item.ID, size))
+		}
+	}
+
+	if len(alpha) > 0 {
+		md.Set(group, "AlphaImage", strings.Join(alpha, ", "), -1, "auxC")
+	}
+	if len(apple) > 0 {
+		md.Set(GroupApple, "AuxiliaryImages", strings.Join(apple, ", "), -1, "auxC")
+	}
+}
+
+// reportColour stores a colour information box: either coded (nclx)
+// parameters or an embedded ICC profile (rICC/prof)
+func (p *HEIFParser) reportColour(prop isoBox, md *Metadata, group string) {
+	c := &byteCursor{data: prop.Payload}
+	colourType := c.fourCC()
+	if c.err != nil {
+		return
+	}
+
+	switch colourType {
+	case "nclx":
+		primaries, transfer, matrix := c.u16(), c.u16(), c.u16()
+		fullRange := c.u8() >> 7
+		if c.err != nil {
+			return
+		}
+		md.Set(group, "ColorType", "nclx", prop.Offset, "colr")
+		md.SetRaw(group, "ColorPrimaries", primaries, lookupCode(colourPrimariesNames, int(primaries)), prop.Offset, "colr")
+		md.SetRaw(group, "TransferCharacteristics", transfer, lookupCode(transferCharacteristicsNames, int(transfer)), prop.Offset, "colr")
+		md.SetRaw(group, "MatrixCoefficients", matrix, lookupCode(matrixCoefficientsNames, int(matrix)), prop.Offset, "colr")
+		if fullRange == 1 {
+			md.Set(group, "VideoFullRange", "Full", prop.Offset, "colr")
+		} else {
+			md.Set(group, "VideoFullRange", "Limited", prop.Offset, "colr")
+		}
+
+	case "rICC", "prof":
+		md.Set(group, "ColorType", colourType, prop.Offset, "colr")
+		parseICCProfile(md, prop.Payload[4:], prop.PayloadOffset+4, "colr")
+	}
+}
+
+// Coding-independent code points (ITU-T H.273)
+var (
+	colourPrimariesNames = map[int]string{
+		1:  "BT.709",
+		2:  "Unspecified",
+		4:  "BT.470 System M",
+		5:  "BT.470 System B/G",
+		6:  "BT.601",
+		7:  "SMPTE 240M",
+		8:  "Generic film",
+		9:  "BT.2020",
+		10: "SMPTE ST 428-1 (XYZ)",
+		11: "SMPTE RP 431-2 (DCI-P3)",
+		12: "SMPTE EG 432-1 (Display P3)",
+		22: "EBU Tech 3213",
+	}
+
+	transferCharacteristicsNames = map[int]string{
+		1:  "BT.709",
+		2:  "Unspecified",
+		4:  "Gamma 2.2",
+		5:  "Gamma 2.8",
+		6:  "BT.601",
+		7:  "SMPTE 240M",
+		8:  "Linear",
+		13: "sRGB",
+		14: "BT.2020 10-bit",
+		15: "BT.2020 12-bit",
+		16: "SMPTE ST 2084 (PQ)",
+		17: "SMPTE ST 428-1",
+		18: "ARIB STD-B67 (HLG)",
+	}
+
+	matrixCoefficientsNames = map[int]string{
+		0:  "Identity (RGB)",
+		1:  "BT.709",
+		2:  "Unspecified",
+		5:  "BT.470 System B/G",
+		6:  "BT.601",
+		7:  "SMPTE 240M",
+		8:  "YCgCo",
+		9:  "BT.2020 non-constant luminance",
+		10: "BT.2020 constant luminance",
+		14: "ICtCp",
+	}
+)
+
+// lookupCode returns the name of a code point, or "Unknown (n)"
+func lookupCode(names map[int]string, code int) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown (%d)", code)
+}
+
+// readHEIFFile decodes the top-level boxes of a HEIF/AVIF file
+func readHEIFFile(data []byte) (*heifFile, error) {
+	f := &heifFile{data: data, items: make(map[uint32]*heifItem)}
+
+	boxes := readBoxes(data, 0)
+	if len(boxes) == 0 || boxes[0].Type != "ftyp" {
+		return nil, fmt.Errorf("not a valid HEIF file (missing ftyp box)")
+	}
+	f.boxes = boxes
+
+	ftyp := boxes[0]
+	if len(ftyp.Payload) >= 8 {
+		f.majorBrand = string(ftyp.Payload[0:4])
+		f.minorVersion = binary.BigEndian.Uint32(ftyp.Payload[4:8])
+		for i := 8; i+4 <= len(ftyp.Payload); i += 4 {
+			f.compatibleBrands = append(f.compatibleBrands, string(ftyp.Payload[i:i+4]))
+		}
+	}
+
+	meta, ok := findBox(boxes, "meta")
+	if !ok {
+		return f, nil
+	}
+
+	for _, box := range meta.children(4) {
+		var err error
+		switch box.Type {
+		case "hdlr":
+			// version/flags, pre_defined, handler_type
+			if len(box.Payload) >= 12 {
+				f.handler = string(box.Payload[8:12])
+			}
+		case "pitm":
+			err = f.readPrimaryItem(box)
+		case "iinf":
+			err = f.readItemInfo(box)
+		case "iloc":
+			err = f.readItemLocations(box)
+		case "iprp":
+			err = f.readItemProperties(box)
+		case "idat":
+			idat := box
+			f.idat = &idat
+		}
+		if err != nil {
+			f.errors = append(f.errors, fmt.Sprintf("invalid %s box at offset %d: %v", box.Type, box.Offset, err))
+		}
+	}
+
+	return f, nil
+}
+
+// readPrimaryItem decodes the primary item box (pitm)
+func (f *heifFile) readPrimaryItem(box isoBox) error {
+	version, _, _ := box.fullBoxHeader()
+	c := &byteCursor{data: box.Payload, pos: 4}
+	if version == 0 {
+		f.primaryID = uint32(c.u16())
+	} else {
+		f.primaryID = c.u32()
+	}
+	return c.err
+}
+
+// item returns the item with the given ID, creating it if necessary
+// (iinf, iloc and ipma may appear in any order)
+func (f *heifFile) item(id uint32) *heifItem {
+	item, ok := f.items[id]
+	if !ok {
+		item = &heifItem{ID: id}
+		f.items[id] = item
+		f.itemOrder = append(f.itemOrder, id)
+	}
+	return item
+}
+
+// readItemInfo decodes the item information box (iinf) and its infe children
+func (f *heifFile) readItemInfo(box isoBox) error {
+	version, _, _ := box.fullBoxHeader()
+	skip := 4 + 2
+	if version > 0 {
+		skip = 4 + 4
+	}
+
+	for _, infe := range box.children(skip) {
+		if infe.Type != "infe" {
+			continue
+		}
+
+		infeVersion, _, _ := infe.fullBoxHeader()
+		c := &byteCursor{data: infe.Payload, pos: 4}
+
+		var item *heifItem
+		if infeVersion >= 2 {
+			if infeVersion == 2 {
+				item = f.item(uint32(c.u16()))
+			} else {
+				item = f.item(c.u32())
+			}
+			c.u16() // item_protection_index
+			item.Type = c.fourCC()
+			item.Name = c.cString()
+			if item.Type == "mime" {
+				item.ContentType = c.cString()
+			}
+		} else {
+			// Legacy entries have no item type; the content type identifies them
+			item = f.item(uint32(c.u16()))
+			c.u16() // item_protection_index
+			item.Name = c.cString()
+			item.ContentType = c.cString()
+			if item.ContentType != "" {
+				item.Type = "mime"
+			}
+		}
+		if c.err != nil {
+			return c.err
+		}
+	}
+
+	return nil
+}
+
+// readItemLocations decodes the item location box (iloc)
+func (f *heifFile) readItemLocations(box isoBox) error {
+	version, _, _ := box.fullBoxHeader()
+	c := &byteCursor{data: box.Payload, pos: 4}
+
+	sizes := c.u8()
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0x0F)
+	sizes = c.u8()
+	baseOffsetSize, indexSize := int(sizes>>4), int(sizes&0x0F)
+	if version == 0 {
+		indexSize = 0
+	}
+
+	var itemCount uint32
+	if version < 2 {
+		itemCount = uint32(c.u16())
+	} else {
+		itemCount = c.u32()
+	}
+
+	for i := uint32(0); i < itemCount && c.err == nil; i++ {
+		var item *heifItem
+		if version < 2 {
+			item = f.item(uint32(c.u16()))
+		} else {
+			item = f.item(c.u32())
+		}
+		if version == 1 || version == 2 {
+			item.ConstructionMethod = int(c.u16() & 0x0F)
+		}
+		c.u16() // data_reference_index
+		item.BaseOffset = c.uintN(baseOffsetSize)
+
+		// Extents with 0-byte fields cost no input, so bound the count by
+		// the bytes left in the box
+		extentCount := int(c.u16())
+		extentSize := indexSize + offsetSize + lengthSize
+		if extentSize == 0 {
+			extentSize = 1
+		}
+		item.Extents = nil
+		if extentCount*extentSize > len(c.data)-c.pos {
+			return fmt.Errorf("item %d has more extents than the box holds", item.ID)
+		}
+		for j := 0; j < extentCount && c.err == nil; j++ {
+			c.uintN(indexSize) // extent_index
+			offset := c.uintN(offsetSize)
+			length := c.uintN(lengthSize)
+			item.Extents = append(item.Extents, heifExtent{Offset: offset, Length: length})
+		}
+		if c.err != nil {
+			// A truncated entry leaves the item without a location
+			item.Extents = nil
+		}
+	}
+
+	return c.err
+}
+
+// readItemProperties decodes the item properties box (iprp): the property
+// container (ipco) and the item-to-property associations (ipma)
+func (f *heifFile) readItemProperties(box isoBox) error {
+	children := box.children(0)
+
+	if ipco, ok := findBox(children, "ipco"); ok {
+		f.properties = ipco.children(0)
+	}
+
+	for _, ipma := range children {
+		if ipma.Type != "ipma" {
+			continue
+		}
+
+		version, flags, _ := ipma.fullBoxHeader()
+		c := &byteCursor{data: ipma.Payload, pos: 4}
+
+		entryCount := c.u32()
+		for i := uint32(0); i < entryCount && c.err == nil; i++ {
+			var item *heifItem
+			if version < 1 {
+				item = f.item(uint32(c.u16()))
+			} else {
+				item = f.item(c.u32())
+			}
+
+			associations := int(c.u8())
+			for j := 0; j < associations && c.err == nil; j++ {
+				// The top bit marks the property as essential
+				var index int
+				if flags&1 != 0 {
+					index = int(c.u16() & 0x7FFF)
+				} else {
+					index = int(c.u8() & 0x7F)
+				}
+				if index > 0 {
+					item.Properties = append(item.Properties, index)
+				}
+			}
+		}
+		if c.err != nil {
+			return c.err
+		}
+	}
+
+	return nil
+}
+
+// itemData returns the bytes of an item and the absolute file offset of its first extent
+func (f *heifFile) itemData(item *heifItem) ([]byte, int, error) {
+	var source []byte
+	var sourceOffset int
+
+	switch item.ConstructionMethod {
+	case 0: // file offset
+		source = f.data
+	case 1: // idat offset
+		if f.idat == nil {
+			return nil, -1, fmt.Errorf("item %d refers to a missing idat box", item.ID)
+		}
+		source = f.idat.Payload
+		sourceOffset = f.idat.PayloadOffset
+	default:
+		return nil, -1, fmt.Errorf("item %d uses unsupported construction method %d", item.ID, item.ConstructionMethod)
+	}
+
+	if len(item.Extents) == 0 {
+		return nil, -1, fmt.Errorf("item %d has no location", item.ID)
+	}
+
+	var out []byte
+	firstOffset := -1
+	for _, extent := range item.Extents {
+		start := item.BaseOffset + extent.Offset
+		length := extent.Length
+		if length == 0 {
+			// Zero length means the rest of the source
+			if start > uint64(len(source)) {
+				return nil, -1, fmt.Errorf("item %d extent out of range", item.ID)
+			}
+			length = uint64(len(source)) - start
+		}
+		if start > uint64(len(source)) || length > uint64(len(source))-start {
+			return nil, -1, fmt.Errorf("item %d extent out of range", item.ID)
+		}
+		// An item cannot be larger than the data it is taken from
+		if length > uint64(len(source)-len(out)) {
+			return nil, -1, fmt.Errorf("item %d is larger than its source", item.ID)
+		}
+		if firstOffset < 0 {
+			firstOffset = sourceOffset + int(start)
+		}
+		out = append(out, source[start:start+length]...)
+	}
+
+	return out, firstOffset, nil
 }
 
 // SupportsFormat checks if this parser supports the given format
@@ -0,0 +1,312 @@
+package parser
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// ICC profiles embedded by the containers (JPEG APP2, PNG iCCP, WebP
+// ICCP, HEIF colr, TIFF InterColorProfile, Photoshop 0x040F) are decoded
+// here. A profile is a 128-byte header followed by a tag table.
+
+// maxICCProfileSize bounds inflated PNG profiles
+const maxICCProfileSize = 4 << 20
+
+// iccSignature starts the JPEG APP2 segments carrying an ICC profile,
+// followed by the 1-based chunk number and the number of chunks
+const iccSignature = "ICC_PROFILE\x00"
+
+var iccDeviceClasses = map[string]string{
+	"scnr": "Input Device Profile",
+	"mntr": "Display Device Profile",
+	"prtr": "Output Device Profile",
+	"link": "DeviceLink Profile",
+	"spac": "ColorSpace Conversion Profile",
+	"abst": "Abstract Profile",
+	"nmcl": "NamedColor Profile",
+}
+
+var iccPlatforms = map[string]string{
+	"APPL": "Apple Computer Inc.",
+	"MSFT": "Microsoft Corporation",
+	"SGI ": "Silicon Graphics Inc.",
+	"SUNW": "Sun Microsystems Inc.",
+	"TGNT": "Taligent Inc.",
+}
+
+var iccRenderingIntents = map[int]string{
+	0: "Perceptual",
+	1: "Media-Relative Colorimetric",
+	2: "Saturation",
+	3: "ICC-Absolute Colorimetric",
+}
+
+// iccTags lists the reported tags; text tags hold desc/text/mluc data,
+// the others XYZ or tone curve data
+var iccTags = []struct {
+	signature string
+	name      string
+}{
+	{"desc", "ProfileDescription"},
+	{"cprt", "ProfileCopyright"},
+	{"dmnd", "DeviceMfgDesc"},
+	{"dmdd", "DeviceModelDesc"},
+	{"wtpt", "MediaWhitePoint"},
+	{"bkpt", "MediaBlackPoint"},
+	{"lumi", "Luminance"},
+	{"rXYZ", "RedMatrixColumn"},
+	{"gXYZ", "GreenMatrixColumn"},
+	{"bXYZ", "BlueMatrixColumn"},
+	{"rTRC", "RedTRC"},
+	{"gTRC", "GreenTRC"},
+	{"bTRC", "BlueTRC"},
+	{"kTRC", "GrayTRC"},
+}
+
+// parseICCProfile reports the header and main tags of an ICC profile
+// offset is the file offset of data
+func parseICCProfile(md *Metadata, data []byte, offset int, source string) {
+	if len(data) < 132 || string(data[36:40]) != "acsp" {
+		md.Set(GroupErrors, "ICC_ParseError", fmt.Sprintf("invalid ICC profile header (%d bytes)", len(data)), offset, source)
+		return
+	}
+	if size := int(binary.BigEndian.Uint32(data[0:4])); size > 0 && size < len(data) {
+		data = data[:size]
+	}
+
+	set := func(name, value string) {
+		if value != "" {
+			md.Set(GroupICC, name, value, offset, source)
+		}
+	}
+
+	set("ProfileCMMType", iccText(data[4:8]))
+	set("ProfileVersion", fmt.Sprintf("%d.%d.%d", data[8], data[9]>>4, data[9]&0x0F))
+	set("ProfileClass", lookupSignature(iccDeviceClasses, string(data[12:16])))
+	set("ColorSpaceData", iccText(data[16:20]))
+	set("ProfileConnectionSpace", iccText(data[20:24]))
+	if date := iccDateTime(data[24:36]); date != "" {
+		set("ProfileDateTime", date)
+	}
+	set("PrimaryPlatform", lookupSignature(iccPlatforms, string(data[40:44])))
+	set("DeviceManufacturer", iccText(data[48:52]))
+	set("DeviceModel", iccText(data[52:56]))
+	set("RenderingIntent", lookupCode(iccRenderingIntents, int(binary.BigEndian.Uint32(data[64:68]))))
+	set("ConnectionSpaceIlluminant", iccXYZ(data[68:80]))
+	set("ProfileCreator", iccText(data[80:84]))
+	if id := data[84:100]; !bytes.Equal(id, make([]byte, 16)) {
+		set("ProfileID", fmt.Sprintf("%x", id))
+	}
+
+	tags := iccTagTable(data)
+	for _, t := range iccTags {
+		tag, ok := tags[t.signature]
+		if !ok {
+			continue
+		}
+		switch t.signature {
+		case "desc", "cprt", "dmnd", "dmdd":
+			set(t.name, iccTextTag(tag))
+		case "rTRC", "gTRC", "bTRC", "kTRC":
+			set(t.name, iccCurve(tag))
+		default:
+			if len(tag) >= 20 && string(tag[:4]) == "XYZ " {
+				set(t.name, iccXYZ(tag[8:20]))
+			}
+		}
+	}
+
+	summary := fmt.Sprintf("(%d bytes)", len(data))
+	if desc := md.Get(GroupICC, "ProfileDescription"); desc != nil {
+		summary = desc.Value + " " + summary
+	}
+	md.Set(GroupICC, "Profile", summary, offset, source)
+}
+
+// iccTagTable returns the data of each tag by signature
+func iccTagTable(data []byte) map[string][]byte {
+	tags := make(map[string][]byte)
+	count := int(binary.BigEndian.Uint32(data[128:132]))
+	for i := 0; i < count; i++ {
+		entry := 132 + i*12
+		if entry+12 > len(data) {
+			break
+		}
+		tagOffset := int(binary.BigEndian.Uint32(data[entry+4 : entry+8]))
+		tagSize := int(binary.BigEndian.Uint32(data[entry+8 : entry+12]))
+		if tagOffset < 0 || tagSize < 0 || tagOffset+tagSize > len(data) || tagOffset+tagSize < tagOffset {
+			continue
+		}
+		tags[string(data[entry:entry+4])] = data[tagOffset : tagOffset+tagSize]
+	}
+	return tags
+}
+
+// iccTextTag decodes a textDescriptionType (v2), textType or
+// multiLocalizedUnicodeType (v4) tag, preferring English for the latter
+func iccTextTag(tag []byte) string {
+	if len(tag) < 12 {
+		return ""
+	}
+	switch string(tag[:4]) {
+	case "desc":
+		n := int(binary.BigEndian.Uint32(tag[8:12]))
+		if n < 0 || 12+n > len(tag) {
+			return ""
+		}
+		return iccText(tag[12 : 12+n])
+	case "text":
+		return iccText(tag[8:])
+	case "mluc":
+		if len(tag) < 16 {
+			return ""
+		}
+		count := int(binary.BigEndian.Uint32(tag[8:12]))
+		recordSize := int(binary.BigEndian.Uint32(tag[12:16]))
+		if recordSize < 12 {
+			return ""
+		}
+		text := ""
+		for i := 0; i < count; i++ {
+			record := 16 + i*recordSize
+			if record+12 > len(tag) {
+				break
+			}
+			length := int(binary.BigEndian.Uint32(tag[record+4 : record+8]))
+			start := int(binary.BigEndian.Uint32(tag[record+8 : record+12]))
+			if start < 0 || length < 0 || start+length > len(tag) {
+				continue
+			}
+			units := make([]uint16, length/2)
+			for j := range units {
+				units[j] = binary.BigEndian.Uint16(tag[start+2*j:])
+			}
+			value := strings.TrimRight(string(utf16.Decode(units)), "\x00")
+			if text == "" || string(tag[record:record+2]) == "en" {
+				text = value
+			}
+			if string(tag[record:record+4]) == "enUS" {
+				break
+			}
+		}
+		return text
+	}
+	return ""
+}
+
+// iccCurve describes a curveType or parametricCurveType tone curve
+func iccCurve(tag []byte) string {
+	if len(tag) < 12 {
+		return ""
+	}
+	switch string(tag[:4]) {
+	case "curv":
+		count := binary.BigEndian.Uint32(tag[8:12])
+		switch {
+		case count == 0:
+			return "Linear"
+		case count == 1 && len(tag) >= 14:
+			// u8Fixed8Number gamma
+			return "Gamma " + formatDecimal(float64(binary.BigEndian.Uint16(tag[12:14]))/256, 3)
+		}
+		return fmt.Sprintf("Curve (%d points)", count)
+	case "para":
+		if len(tag) < 16 {
+			return ""
+		}
+		function := binary.BigEndian.Uint16(tag[8:10])
+		gamma := float64(int32(binary.BigEndian.Uint32(tag[12:16]))) / 65536
+		return fmt.Sprintf("Parametric (type %d, gamma %s)", function, formatDecimal(gamma, 3))
+	}
+	return ""
+}
+
+// iccXYZ renders three s15Fixed16 numbers
+func iccXYZ(data []byte) string {
+	var parts []string
+	for i := 0; i+4 <= len(data) && i < 12; i += 4 {
+		v := float64(int32(binary.BigEndian.Uint32(data[i:i+4]))) / 65536
+		parts = append(parts, formatDecimal(v, 5))
+	}
+	return strings.Join(parts, " ")
+}
+
+// iccDateTime renders the header creation date (six 16-bit fields)
+func iccDateTime(data []byte) string {
+	var f [6]uint16
+	for i := range f {
+		f[i] = binary.BigEndian.Uint16(data[i*2:])
+	}
+	if f[0] == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%04d:%02d:%02d %02d:%02d:%02d", f[0], f[1], f[2], f[3], f[4], f[5])
+}
+
+// iccText trims the padding of a signature or ASCII field
+func iccText(data []byte) string {
+	return strings.TrimRight(string(data), "\x00 ")
+}
+
+// lookupSignature names a 4-character signature, keeping unknown ones
+func lookupSignature(names map[string]string, signature string) string {
+	if name, ok := names[signature]; ok {
+		return name
+	}
+	return iccText([]byte(signature))
+}
+
+// iccChunk is one APP2 segment of a JPEG ICC profile
+type iccChunk struct {
+	sequence int
+	count    int
+	data     []byte
+	offset   int
+}
+
+// reportJPEGICCProfile reassembles the APP2 chunks by sequence number and
+// decodes the profile
+func reportJPEGICCProfile(md *Metadata, chunks []iccChunk, source string) {
+	count := chunks[0].count
+	ordered := make([][]byte, count)
+	for _, chunk := range chunks {
+		if chunk.count != count || chunk.sequence < 1 || chunk.sequence > count || ordered[chunk.sequence-1] != nil {
+			md.Set(GroupErrors, "ICC_ParseError", fmt.Sprintf("invalid ICC_PROFILE chunk %d of %d", chunk.sequence, chunk.count), chunk.offset, source)
+			return
+		}
+		ordered[chunk.sequence-1] = chunk.data
+	}
+
+	var profile []byte
+	for i, data := range ordered {
+		if data == nil {
+			md.Set(GroupErrors, "ICC_ParseError", fmt.Sprintf("missing ICC_PROFILE chunk %d of %d", i+1, count), chunks[0].offset, source)
+			return
+		}
+		profile = append(profile, data...)
+	}
+	parseICCProfile(md, profile, chunks[0].offset, source)
+}
+
+// inflateICCProfile decompresses a zlib-compressed profile (PNG iCCP)
+func inflateICCProfile(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(reader, maxICCProfileSize+1)); err != nil {
+		return nil, err
+	}
+	if buf.Len() > maxICCProfileSize {
+		return nil, fmt.Errorf("ICC profile exceeds %d bytes", maxICCProfileSize)
+	}
+	return buf.Bytes(), nil
+}
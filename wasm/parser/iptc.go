@@ -0,0 +1,250 @@
+package parser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// IPTC-IIM datasets
+// Each dataset is 0x1C, the record and dataset numbers and a 2-byte size;
+// when the top bit of the size is set, its low bits give the length of
+// an extended size field that follows
+const (
+	iptcTagMarker       = 0x1C
+	iptcCodedCharacters = 90 // record 1
+)
+
+// iptcDataset describes an IPTC dataset; Repeatable values are joined
+type iptcDataset struct {
+	Name       string
+	Repeatable bool
+	Format     func(value string) string
+}
+
+var iptcEnvelopeRecord = map[int]iptcDataset{
+	0:   {Name: "EnvelopeRecordVersion"},
+	5:   {Name: "Destination", Repeatable: true},
+	20:  {Name: "FileFormat"},
+	30:  {Name: "ServiceIdentifier"},
+	40:  {Name: "EnvelopeNumber"},
+	60:  {Name: "EnvelopePriority"},
+	70:  {Name: "DateSent", Format: formatIPTCDate},
+	80:  {Name: "TimeSent", Format: formatIPTCTime},
+	90:  {Name: "CodedCharacterSet"},
+	100: {Name: "UniqueObjectName"},
+}
+
+var iptcApplicationRecord = map[int]iptcDataset{
+	0:   {Name: "ApplicationRecordVersion"},
+	3:   {Name: "ObjectTypeReference"},
+	4:   {Name: "ObjectAttributeReference", Repeatable: true},
+	5:   {Name: "ObjectName"},
+	7:   {Name: "EditStatus"},
+	10:  {Name: "Urgency"},
+	12:  {Name: "SubjectReference", Repeatable: true},
+	15:  {Name: "Category"},
+	20:  {Name: "SupplementalCategories", Repeatable: true},
+	22:  {Name: "FixtureIdentifier"},
+	25:  {Name: "Keywords", Repeatable: true},
+	26:  {Name: "ContentLocationCode", Repeatable: true},
+	27:  {Name: "ContentLocationName", Repeatable: true},
+	30:  {Name: "ReleaseDate", Format: formatIPTCDate},
+	35:  {Name: "ReleaseTime", Format: formatIPTCTime},
+	37:  {Name: "ExpirationDate", Format: formatIPTCDate},
+	38:  {Name: "ExpirationTime", Format: formatIPTCTime},
+	40:  {Name: "SpecialInstructions"},
+	42:  {Name: "ActionAdvised"},
+	45:  {Name: "ReferenceService", Repeatable: true},
+	47:  {Name: "ReferenceDate", Repeatable: true, Format: formatIPTCDate},
+	50:  {Name: "ReferenceNumber", Repeatable: true},
+	55:  {Name: "DateCreated", Format: formatIPTCDate},
+	60:  {Name: "TimeCreated", Format: formatIPTCTime},
+	62:  {Name: "DigitalCreationDate", Format: formatIPTCDate},
+	63:  {Name: "DigitalCreationTime", Format: formatIPTCTime},
+	65:  {Name: "OriginatingProgram"},
+	70:  {Name: "ProgramVersion"},
+	75:  {Name: "ObjectCycle", Format: formatIPTCObjectCycle},
+	80:  {Name: "By-line", Repeatable: true},
+	85:  {Name: "By-lineTitle", Repeatable: true},
+	90:  {Name: "City"},
+	92:  {Name: "Sub-location"},
+	95:  {Name: "Province-State"},
+	100: {Name: "Country-PrimaryLocationCode"},
+	101: {Name: "Country-PrimaryLocationName"},
+	103: {Name: "OriginalTransmissionReference"},
+	105: {Name: "Headline"},
+	110: {Name: "Credit"},
+	115: {Name: "Source"},
+	116: {Name: "CopyrightNotice"},
+	118: {Name: "Contact", Repeatable: true},
+	120: {Name: "Caption-Abstract"},
+	121: {Name: "LocalCaption"},
+	122: {Name: "Writer-Editor", Repeatable: true},
+	130: {Name: "ImageType"},
+	131: {Name: "ImageOrientation", Format: formatIPTCOrientation},
+	135: {Name: "LanguageIdentifier"},
+	184: {Name: "JobID"},
+	187: {Name: "MasterDocumentID"},
+	188: {Name: "ShortDocumentID"},
+	221: {Name: "Prefs"},
+	228: {Name: "ClassifyState"},
+	230: {Name: "SimilarityIndex"},
+	231: {Name: "DocumentNotes"},
+	232: {Name: "DocumentHistory"},
+}
+
+// iptcRecords maps the record numbers reported by name
+var iptcRecords = map[int]map[int]iptcDataset{
+	1: iptcEnvelopeRecord,
+	2: iptcApplicationRecord,
+}
+
+// iptcValue is a dataset value collected before repeatable values are
+// joined
+type iptcValue struct {
+	name   string
+	values []string
+	offset int
+}
+
+// parseIPTC reports the IPTC-IIM datasets of records 1 and 2
+// Text is UTF-8 when dataset 1:90 holds the ISO 2022 escape ESC % G;
+// otherwise valid UTF-8 is kept and anything else is read as Latin-1
+func parseIPTC(md *Metadata, data []byte, offset int, source string) {
+	utf8Text := false
+	var collected []*iptcValue
+	byName := make(map[string]*iptcValue)
+
+	pos := 0
+	for pos+5 <= len(data) && data[pos] == iptcTagMarker {
+		record, dataset := int(data[pos+1]), int(data[pos+2])
+		size := int(binary.BigEndian.Uint16(data[pos+3 : pos+5]))
+		start := pos + 5
+		if size&0x8000 != 0 {
+			// Extended dataset: the low bits give the size of the length field
+			n := size & 0x7FFF
+			if n > 4 || start+n > len(data) {
+				break
+			}
+			size = 0
+			for _, b := range data[start : start+n] {
+				size = size<<8 | int(b)
+			}
+			start += n
+		}
+		if size < 0 || start+size > len(data) {
+			md.Set(GroupErrors, "IPTC_ParseError", fmt.Sprintf("dataset %d:%d truncated", record, dataset), offset+pos, source)
+			break
+		}
+		value := data[start : start+size]
+		datasetOffset := offset + pos
+		pos = start + size
+
+		if record == 1 && dataset == iptcCodedCharacters {
+			utf8Text = string(value) == "\x1b%G"
+		}
+		info, ok := iptcRecords[record][dataset]
+		if !ok {
+			continue
+		}
+
+		var text string
+		switch {
+		case dataset == 0 && len(value) == 2:
+			// Record versions are binary
+			text = fmt.Sprintf("%d", binary.BigEndian.Uint16(value))
+		case record == 1 && dataset == iptcCodedCharacters:
+			text = formatIPTCCharacterSet(value)
+		default:
+			text = decodeIPTCText(value, utf8Text)
+		}
+		if info.Format != nil {
+			text = info.Format(text)
+		}
+
+		if entry, seen := byName[info.Name]; seen && info.Repeatable {
+			entry.values = append(entry.values, text)
+			continue
+		}
+		entry := &iptcValue{name: info.Name, values: []string{text}, offset: datasetOffset}
+		byName[info.Name] = entry
+		collected = append(collected, entry)
+	}
+
+	for _, entry := range collected {
+		md.Set(GroupIPTC, entry.name, strings.Join(entry.values, ", "), entry.offset, source)
+	}
+}
+
+// decodeIPTCText converts a text dataset to a Go string
+func decodeIPTCText(value []byte, utf8Text bool) string {
+	text := strings.TrimRight(string(value), "\x00")
+	if utf8Text || utf8.ValidString(text) {
+		return text
+	}
+	runes := make([]rune, len(text))
+	for i := 0; i < len(text); i++ {
+		runes[i] = rune(text[i])
+	}
+	return string(runes)
+}
+
+// formatIPTCCharacterSet names the ISO 2022 escape sequence of 1:90
+func formatIPTCCharacterSet(value []byte) string {
+	switch string(value) {
+	case "\x1b%G":
+		return "UTF8"
+	case "\x1b.A":
+		return "Latin1"
+	case "\x1b$B":
+		return "JIS X 0208"
+	}
+	return fmt.Sprintf("% X", value)
+}
+
+// formatIPTCDate renders CCYYMMDD as "CCYY:MM:DD"
+func formatIPTCDate(v string) string {
+	if len(v) != 8 {
+		return v
+	}
+	return v[:4] + ":" + v[4:6] + ":" + v[6:]
+}
+
+// formatIPTCTime renders HHMMSS±HHMM as "HH:MM:SS±HH:MM"
+func formatIPTCTime(v string) string {
+	switch len(v) {
+	case 6:
+		return v[:2] + ":" + v[2:4] + ":" + v[4:]
+	case 11:
+		return v[:2] + ":" + v[2:4] + ":" + v[4:6] + v[6:9] + ":" + v[9:]
+	}
+	return v
+}
+
+// formatIPTCObjectCycle names the a/p/b object cycle codes
+func formatIPTCObjectCycle(v string) string {
+	switch v {
+	case "a":
+		return "Morning"
+	case "p":
+		return "Evening"
+	case "b":
+		return "Both Morning and Evening"
+	}
+	return v
+}
+
+// formatIPTCOrientation names the P/L/S image orientation codes
+func formatIPTCOrientation(v string) string {
+	switch v {
+	case "P":
+		return "Portrait"
+	case "L":
+		return "Landscape"
+	case "S":
+		return "Square"
+	}
+	return v
+}
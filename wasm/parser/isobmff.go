@@ -0,0 +1,200 @@
+package parser
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// isoBox is one box of an ISO Base Media File Format (ISO/IEC 14496-12) file
+type isoBox struct {
+	Type string
+
+	// Offset is the absolute file offset of the box header
+	Offset int
+
+	// Payload is the box content after the header (and the extended type for uuid boxes)
+	Payload []byte
+
+	// PayloadOffset is the absolute file offset of Payload
+	PayloadOffset int
+
+	// UserType is the 16-byte extended type of 'uuid' boxes
+	UserType []byte
+}
+
+// readBoxes splits data into sibling boxes; base is the absolute file offset of data
+// Parsing stops at the first malformed box header
+func readBoxes(data []byte, base int) []isoBox {
+	var boxes []isoBox
+
+	offset := 0
+	for offset+8 <= len(data) {
+		size := uint64(binary.BigEndian.Uint32(data[offset : offset+4]))
+		boxType := string(data[offset+4 : offset+8])
+		headerSize := 8
+
+		switch size {
+		case 0:
+			// Box extends to the end of the enclosing container
+			size = uint64(len(data) - offset)
+		case 1:
+			// 64-bit largesize follows the type
+			if offset+16 > len(data) {
+				return boxes
+			}
+			size = binary.BigEndian.Uint64(data[offset+8 : offset+16])
+			headerSize = 16
+		}
+
+		if size < uint64(headerSize) || size > uint64(len(data)-offset) {
+			return boxes
+		}
+
+		box := isoBox{Type: boxType, Offset: base + offset}
+		payloadStart := offset + headerSize
+		if boxType == "uuid" {
+			if payloadStart+16 > offset+int(size) {
+				return boxes
+			}
+			box.UserType = data[payloadStart : payloadStart+16]
+			payloadStart += 16
+		}
+		box.Payload = data[payloadStart : offset+int(size)]
+		box.PayloadOffset = base + payloadStart
+
+		boxes = append(boxes, box)
+		offset += int(size)
+	}
+
+	return boxes
+}
+
+// children returns the boxes nested in a container box
+// skip is the number of payload bytes before the first child
+// (4 for full boxes such as 'meta' that carry version and flags)
+func (b isoBox) children(skip int) []isoBox {
+	if skip > len(b.Payload) {
+		return nil
+	}
+	return readBoxes(b.Payload[skip:], b.PayloadOffset+skip)
+}
+
+// findBox returns the first box of the given type
+func findBox(boxes []isoBox, boxType string) (isoBox, bool) {
+	for _, b := range boxes {
+		if b.Type == boxType {
+			return b, true
+		}
+	}
+	return isoBox{}, false
+}
+
+// fullBoxHeader returns the version and flags of a full box
+func (b isoBox) fullBoxHeader() (version byte, flags uint32, ok bool) {
+	if len(b.Payload) < 4 {
+		return 0, 0, false
+	}
+	return b.Payload[0], binary.BigEndian.Uint32(b.Payload[0:4]) & 0x00FFFFFF, true
+}
+
+// byteCursor reads big-endian fields sequentially from a box payload
+// Reads past the end set err and return zero values
+type byteCursor struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (c *byteCursor) need(n int) bool {
+	if c.err != nil {
+		return false
+	}
+	if n < 0 || c.pos+n > len(c.data) {
+		c.err = fmt.Errorf("box truncated at offset %d", c.pos)
+		return false
+	}
+	return true
+}
+
+func (c *byteCursor) u8() uint8 {
+	if !c.need(1) {
+		return 0
+	}
+	v := c.data[c.pos]
+	c.pos++
+	return v
+}
+
+func (c *byteCursor) u16() uint16 {
+	if !c.need(2) {
+		return 0
+	}
+	v := binary.BigEndian.Uint16(c.data[c.pos:])
+	c.pos += 2
+	return v
+}
+
+func (c *byteCursor) u32() uint32 {
+	if !c.need(4) {
+		return 0
+	}
+	v := binary.BigEndian.Uint32(c.data[c.pos:])
+	c.pos += 4
+	return v
+}
+
+func (c *byteCursor) u64() uint64 {
+	if !c.need(8) {
+		return 0
+	}
+	v := binary.BigEndian.Uint64(c.data[c.pos:])
+	c.pos += 8
+	return v
+}
+
+// uintN reads an unsigned integer of 0, 4 or 8 bytes (as used by iloc)
+func (c *byteCursor) uintN(size int) uint64 {
+	switch size {
+	case 0:
+		return 0
+	case 4:
+		return uint64(c.u32())
+	case 8:
+		return c.u64()
+	}
+	if c.err == nil {
+		c.err = fmt.Errorf("unsupported field size %d", size)
+	}
+	return 0
+}
+
+func (c *byteCursor) fourCC() string {
+	if !c.need(4) {
+		return ""
+	}
+	v := string(c.data[c.pos : c.pos+4])
+	c.pos += 4
+	return v
+}
+
+// cString reads a NUL-terminated UTF-8 string (a missing terminator ends at the payload end)
+func (c *byteCursor) cString() string {
+	if c.err != nil {
+		return ""
+	}
+	start := c.pos
+	for c.pos < len(c.data) && c.data[c.pos] != 0 {
+		c.pos++
+	}
+	v := string(c.data[start:c.pos])
+	if c.pos < len(c.data) {
+		c.pos++ // skip terminator
+	}
+	return v
+}
+
+func (c *byteCursor) skip(n int) {
+	if c.need(n) {
+		c.pos += n
+	}
+}
@@ -0,0 +1,224 @@
+package parser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// JPEG frame and table segments read before the first scan
+
+// jpegEncodingProcesses names the coding process of each SOFn marker
+var jpegEncodingProcesses = map[byte]string{
+	0xC0: "Baseline DCT, Huffman coding",
+	0xC1: "Extended sequential DCT, Huffman coding",
+	0xC2: "Progressive DCT, Huffman coding",
+	0xC3: "Lossless, Huffman coding",
+	0xC5: "Sequential DCT, differential Huffman coding",
+	0xC6: "Progressive DCT, differential Huffman coding",
+	0xC7: "Lossless, differential Huffman coding",
+	0xC9: "Extended sequential DCT, arithmetic coding",
+	0xCA: "Progressive DCT, arithmetic coding",
+	0xCB: "Lossless, arithmetic coding",
+	0xCD: "Sequential DCT, differential arithmetic coding",
+	0xCE: "Progressive DCT, differential arithmetic coding",
+	0xCF: "Lossless, differential arithmetic coding",
+}
+
+// isSOFMarker reports whether marker is one of SOF0-SOF15
+// (0xC4 DHT, 0xC8 JPG and 0xCC DAC share the range)
+func isSOFMarker(marker byte) bool {
+	_, ok := jpegEncodingProcesses[marker]
+	return ok
+}
+
+// jpegTables counts the Huffman and quantization tables defined by the
+// DHT and DQT segments; offsets are those of the first segment of each
+type jpegTables struct {
+	dc, ac    int
+	dhtOffset int
+	quant     int
+	quant16   bool
+	dqtOffset int
+}
+
+// reportJPEGFrame reports a start-of-frame segment: the encoded image
+// size, sample precision, components and chroma subsampling
+func reportJPEGFrame(md *Metadata, marker byte, data []byte, offset int, source string) {
+	c := &byteCursor{data: data}
+	precision := c.u8()
+	height, width := c.u16(), c.u16()
+	components := int(c.u8())
+	if c.err != nil {
+		md.Set(GroupErrors, "JPEG_FrameError", c.err.Error(), offset, source)
+		return
+	}
+
+	type component struct{ id, h, v int }
+	var comps []component
+	for i := 0; i < components; i++ {
+		id, sampling := c.u8(), c.u8()
+		c.skip(1) // quantization table
+		if c.err != nil {
+			break
+		}
+		comps = append(comps, component{int(id), int(sampling >> 4), int(sampling & 0x0F)})
+	}
+
+	md.Set(GroupJPEG, "EncodingProcess", jpegEncodingProcesses[marker], offset, source)
+	md.SetRaw(GroupJPEG, "ImageWidth", int(width), fmt.Sprintf("%d", width), offset, source)
+	md.SetRaw(GroupJPEG, "ImageHeight", int(height), fmt.Sprintf("%d", height), offset, source)
+	md.SetRaw(GroupJPEG, "BitsPerSample", int(precision), fmt.Sprintf("%d", precision), offset, source)
+	md.SetRaw(GroupJPEG, "ColorComponents", components, fmt.Sprintf("%d", components), offset, source)
+
+	if len(comps) == 3 && comps[1].h == comps[2].h && comps[1].v == comps[2].v && comps[1].h > 0 && comps[1].v > 0 {
+		h, v := comps[0].h/comps[1].h, comps[0].v/comps[1].v
+		names := map[[2]int]string{
+			{1, 1}: "4:4:4", {2, 1}: "4:2:2", {2, 2}: "4:2:0",
+			{4, 1}: "4:1:1", {1, 2}: "4:4:0", {4, 2}: "4:1:0",
+		}
+		value := fmt.Sprintf("YCbCr (%d %d)", h, v)
+		if name, ok := names[[2]int{h, v}]; ok {
+			value = "YCbCr" + name + fmt.Sprintf(" (%d %d)", h, v)
+		}
+		md.Set(GroupJPEG, "YCbCrSubSampling", value, offset, source)
+	}
+
+	var factors []string
+	for _, comp := range comps {
+		factors = append(factors, fmt.Sprintf("%dx%d", comp.h, comp.v))
+	}
+	if len(factors) > 0 {
+		md.Set(GroupJPEG, "SamplingFactors", strings.Join(factors, " "), offset, source)
+	}
+}
+
+// countHuffmanTables counts the tables of a DHT segment
+// Each table is a class/ID byte, 16 code length counts and the symbols
+func (t *jpegTables) countHuffmanTables(data []byte, offset int) {
+	if t.dc+t.ac == 0 {
+		t.dhtOffset = offset
+	}
+	pos := 0
+	for pos+17 <= len(data) {
+		class := data[pos] >> 4
+		symbols := 0
+		for _, n := range data[pos+1 : pos+17] {
+			symbols += int(n)
+		}
+		if class == 0 {
+			t.dc++
+		} else {
+			t.ac++
+		}
+		pos += 17 + symbols
+	}
+}
+
+// countQuantizationTables counts the tables of a DQT segment
+// Each table is a precision/ID byte followed by 64 8-bit or 16-bit values
+func (t *jpegTables) countQuantizationTables(data []byte, offset int) {
+	if t.quant == 0 {
+		t.dqtOffset = offset
+	}
+	pos := 0
+	for pos < len(data) {
+		size := 64
+		if data[pos]>>4 == 1 {
+			size = 128
+			t.quant16 = true
+		}
+		if pos+1+size > len(data) {
+			break
+		}
+		t.quant++
+		pos += 1 + size
+	}
+}
+
+// report stores the table counts in the JPEG group
+func (t *jpegTables) report(md *Metadata) {
+	if t.dc+t.ac > 0 {
+		md.Set(GroupJPEG, "HuffmanTables", fmt.Sprintf("%d DC, %d AC", t.dc, t.ac), t.dhtOffset, "DHT")
+	}
+	if t.quant > 0 {
+		precision := "8-bit"
+		if t.quant16 {
+			precision = "16-bit"
+		}
+		md.Set(GroupJPEG, "QuantizationTables", fmt.Sprintf("%d (%s)", t.quant, precision), t.dqtOffset, "DQT")
+	}
+}
+
+// reportRestartInterval reports a DRI segment
+func reportRestartInterval(md *Metadata, data []byte, offset int, source string) {
+	if len(data) < 2 {
+		return
+	}
+	interval := binary.BigEndian.Uint16(data)
+	md.SetRaw(GroupJPEG, "RestartInterval", int(interval), fmt.Sprintf("%d MCUs", interval), offset, source)
+}
+
+// frameSize returns the frame dimensions as "WxH", or "" without a frame
+func frameSize(md *Metadata) string {
+	width, height := md.Get(GroupJPEG, "ImageWidth"), md.Get(GroupJPEG, "ImageHeight")
+	if width == nil || height == nil {
+		return ""
+	}
+	return width.Value + "x" + height.Value
+}
+
+// checkJPEGDimensions flags EXIF dimensions that disagree with the frame
+func checkJPEGDimensions(md *Metadata) {
+	width, height := md.Get(GroupJPEG, "ImageWidth"), md.Get(GroupJPEG, "ImageHeight")
+	exifWidth, exifHeight := md.Get(GroupExif, "PixelXDimension"), md.Get(GroupExif, "PixelYDimension")
+	if width == nil || height == nil || exifWidth == nil || exifHeight == nil {
+		return
+	}
+	if width.Value != exifWidth.Value || height.Value != exifHeight.Value {
+		md.Set(GroupJPEG, "DimensionMismatch", fmt.Sprintf("EXIF %sx%s, frame %sx%s",
+			exifWidth.Value, exifHeight.Value, width.Value, height.Value), width.Offset, width.Source)
+	}
+}
+
+// jpegImageEnd returns the offset just past the EOI marker of the JPEG
+// stream at the start of data, or -1 if the stream is truncated
+// Segments are skipped by their length; entropy-coded data after SOS is
+// scanned for the next marker, since 0xFF is always followed by a stuffed
+// 0x00 or a restart marker inside it
+func jpegImageEnd(data []byte) int {
+	if len(data) < 2 || data[0] != 0xFF || data[1] != 0xD8 {
+		return -1
+	}
+	pos := 2
+	for pos+2 <= len(data) {
+		if data[pos] != 0xFF {
+			return -1
+		}
+		marker := data[pos+1]
+		switch {
+		case marker == 0xFF: // fill byte
+			pos++
+			continue
+		case marker == 0xD9: // EOI
+			return pos + 2
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7): // TEM, RSTn
+			pos += 2
+			continue
+		}
+		if pos+4 > len(data) {
+			return -1
+		}
+		pos += 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+		if marker != 0xDA {
+			continue
+		}
+		for pos+1 < len(data) {
+			if data[pos] == 0xFF && data[pos+1] != 0x00 && (data[pos+1] < 0xD0 || data[pos+1] > 0xD7) {
+				break
+			}
+			pos++
+		}
+	}
+	return -1
+}
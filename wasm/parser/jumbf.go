@@ -0,0 +1,151 @@
+package parser
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// JUMBF (ISO/IEC 19566-5) stores labelled superboxes in ISOBMFF box
+// syntax: each 'jumb' box starts with a 'jumd' description box giving its
+// type UUID and label, followed by content boxes or nested superboxes.
+// C2PA manifest stores are JUMBF trees.
+
+// maxJUMBFDepth bounds the nesting of superboxes
+const maxJUMBFDepth = 16
+
+// jumbfBox is a decoded JUMBF superbox
+type jumbfBox struct {
+	// Type is the first four bytes of the description type UUID, which
+	// C2PA and JUMBF content types spell as ASCII ("c2pa", "cbor", ...)
+	Type string
+
+	Label string
+
+	// Offset is the absolute file offset of the superbox
+	Offset int
+
+	// Payload is the superbox content after its header, which the claim's
+	// hashed URIs cover
+	Payload []byte
+
+	// Content holds the content boxes following the description
+	Content []isoBox
+
+	Children []*jumbfBox
+}
+
+// readJUMBF decodes the superbox at the start of data; base is the
+// absolute file offset of data
+func readJUMBF(data []byte, base int) (*jumbfBox, error) {
+	boxes := readBoxes(data, base)
+	if len(boxes) == 0 || boxes[0].Type != "jumb" {
+		return nil, fmt.Errorf("not a JUMBF superbox")
+	}
+	return parseJUMBF(boxes[0], 0)
+}
+
+// parseJUMBF decodes a 'jumb' box and its nested superboxes
+func parseJUMBF(box isoBox, depth int) (*jumbfBox, error) {
+	if depth > maxJUMBFDepth {
+		return nil, fmt.Errorf("JUMBF nesting too deep")
+	}
+	children := box.children(0)
+	if len(children) == 0 || children[0].Type != "jumd" {
+		return nil, fmt.Errorf("JUMBF superbox at offset %d has no description box", box.Offset)
+	}
+
+	// jumd: type UUID, toggles, then the optional label, ID and hash
+	jumd := children[0].Payload
+	if len(jumd) < 17 {
+		return nil, fmt.Errorf("JUMBF description box too short")
+	}
+	b := &jumbfBox{Type: string(jumd[:4]), Offset: box.Offset, Payload: box.Payload}
+	toggles := jumd[16]
+	if toggles&0x02 != 0 {
+		label := jumd[17:]
+		for i, c := range label {
+			if c == 0 {
+				label = label[:i]
+				break
+			}
+		}
+		b.Label = string(label)
+	}
+
+	for _, child := range children[1:] {
+		if child.Type != "jumb" {
+			b.Content = append(b.Content, child)
+			continue
+		}
+		sub, err := parseJUMBF(child, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		b.Children = append(b.Children, sub)
+	}
+	return b, nil
+}
+
+// child returns the first nested superbox with the given label
+func (b *jumbfBox) child(label string) *jumbfBox {
+	for _, c := range b.Children {
+		if c.Label == label {
+			return c
+		}
+	}
+	return nil
+}
+
+// cbor decodes the first 'cbor' content box and also returns its bytes
+func (b *jumbfBox) cbor() (interface{}, []byte, error) {
+	for _, c := range b.Content {
+		if c.Type == "cbor" {
+			v, err := decodeCBOR(c.Payload)
+			return v, c.Payload, err
+		}
+	}
+	return nil, nil, fmt.Errorf("%s has no CBOR content", b.Label)
+}
+
+// jumbfSegments reassembles the JUMBF boxes carried in JPEG APP11
+// segments. Each segment starts with the "JP" common identifier, the box
+// instance number and a packet sequence number; continuation packets
+// repeat the box header, which is dropped
+type jumbfSegments struct {
+	order  []int
+	boxes  map[int][]byte
+	offset map[int]int
+}
+
+// add appends an APP11 segment; it returns false for other APP11 data
+func (s *jumbfSegments) add(segment []byte, offset int) bool {
+	if len(segment) < 16 || segment[0] != 'J' || segment[1] != 'P' {
+		return false
+	}
+	instance := int(binary.BigEndian.Uint16(segment[2:4]))
+	sequence := binary.BigEndian.Uint32(segment[4:8])
+	payload := segment[8:]
+
+	if s.boxes == nil {
+		s.boxes = make(map[int][]byte)
+		s.offset = make(map[int]int)
+	}
+	existing, ok := s.boxes[instance]
+	if !ok || sequence <= 1 {
+		if !ok {
+			s.order = append(s.order, instance)
+		}
+		s.boxes[instance] = append([]byte(nil), payload...)
+		s.offset[instance] = offset + 8
+		return true
+	}
+
+	header := 8
+	if binary.BigEndian.Uint32(payload[0:4]) == 1 {
+		header = 16
+	}
+	if len(payload) >= header {
+		s.boxes[instance] = append(existing, payload[header:]...)
+	}
+	return true
+}
@@ -0,0 +1,190 @@
+package parser
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// makerNoteDecoder decodes a vendor MakerNote stored at offset (relative
+// to the TIFF header of r) with the given size in bytes
+type makerNoteDecoder func(p *SimpleExifParser, r *tiffReader, offset, size int)
+
+// makerNoteDecoders selects the decoder from the IFD0 Make value
+// Makes are matched case-insensitively on their prefix; new vendors only
+// need an entry here
+var makerNoteDecoders = []struct {
+	make   string
+	decode makerNoteDecoder
+}{
+	{"CANON", parseCanonMakerNote},
+	{"NIKON", parseNikonMakerNote},
+	{"SONY", parseSonyMakerNote},
+	{"FUJIFILM", parseFujifilmMakerNote},
+	{"OLYMPUS", parseOlympusMakerNote},
+	{"OM DIGITAL", parseOlympusMakerNote},
+	{"PANASONIC", parsePanasonicMakerNote},
+	{"APPLE", parseAppleMakerNote},
+	{"DJI", parseDJIMakerNote},
+}
+
+// parseMakerNote decodes the MakerNote of a supported camera vendor
+// MakerNotes of unknown vendors are only reported by size
+func (p *SimpleExifParser) parseMakerNote(r *tiffReader, offset, size int) {
+	if offset < 0 || size <= 0 || offset+size > len(r.data) {
+		return
+	}
+
+	cameraMake := r.md.Get(GroupIFD0, "Make")
+	if cameraMake == nil {
+		return
+	}
+	name := strings.ToUpper(strings.TrimSpace(cameraMake.Value))
+
+	for _, d := range makerNoteDecoders {
+		if strings.HasPrefix(name, d.make) {
+			d.decode(p, r, offset, size)
+			return
+		}
+	}
+}
+
+// makerNoteModel returns the IFD0 Model value, used where the MakerNote
+// layout differs between camera bodies
+func makerNoteModel(r *tiffReader) string {
+	if model := r.md.Get(GroupIFD0, "Model"); model != nil {
+		return model.Value
+	}
+	return ""
+}
+
+// embedded returns a reader for a structure stored at data[start:end]
+// whose offsets are relative to start rather than to the TIFF header
+func (r *tiffReader) embedded(start, end int, byteOrder binary.ByteOrder) *tiffReader {
+	return &tiffReader{
+		data:      r.data[start:end],
+		byteOrder: byteOrder,
+		base:      r.base + start,
+		source:    r.source,
+		md:        r.md,
+	}
+}
+
+// tiffHeader reads the byte order and first IFD offset of a TIFF header
+func tiffHeader(data []byte) (binary.ByteOrder, int, bool) {
+	if len(data) < 8 {
+		return nil, 0, false
+	}
+	var byteOrder binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		byteOrder = binary.LittleEndian
+	case "MM":
+		byteOrder = binary.BigEndian
+	default:
+		return nil, 0, false
+	}
+	return byteOrder, int(byteOrder.Uint32(data[4:8])), true
+}
+
+// parseMakerNoteIFD walks a vendor IFD at offset and reports the tags
+// listed in tags under group. Tags missing from the table are skipped
+// because MakerNotes carry many undocumented binary blocks.
+// expand, if set, is offered each entry first and returns true when it
+// reported the entry itself (e.g. by unpacking an array of fields)
+func (p *SimpleExifParser) parseMakerNoteIFD(r *tiffReader, offset int, group string, tags tagTable,
+	expand func(tag uint16, raw interface{}, entryOffset int) bool) {
+	data, byteOrder := r.data, r.byteOrder
+	if offset < 0 || offset+2 > len(data) {
+		return
+	}
+
+	numEntries := int(byteOrder.Uint16(data[offset : offset+2]))
+	for i := 0; i < numEntries; i++ {
+		entryOffset := offset + 2 + i*12
+		if entryOffset+12 > len(data) {
+			break
+		}
+
+		tag := byteOrder.Uint16(data[entryOffset : entryOffset+2])
+		dataType := byteOrder.Uint16(data[entryOffset+2 : entryOffset+4])
+		count := byteOrder.Uint32(data[entryOffset+4 : entryOffset+8])
+
+		raw, ok := readTagValue(dataType, count, entryOffset+8, data, byteOrder)
+		if !ok {
+			continue
+		}
+		if expand != nil && expand(tag, raw, entryOffset) {
+			continue
+		}
+
+		info, known := tags[tag]
+		if !known {
+			continue
+		}
+		r.checkFormat(group, info, dataType, count, entryOffset)
+		value := formatTagValue(raw)
+		if info.Format != nil {
+			value = info.Format(raw)
+		}
+		if value != "" {
+			r.set(group, tag, info.Name, raw, value, entryOffset)
+		}
+	}
+}
+
+// arrayField names one element of a packed MakerNote array
+// Values are passed to Format as []int32 so the EXIF formatters
+// (formatEnum etc.) can be reused
+type arrayField struct {
+	Index  int
+	Name   string
+	Format func(value interface{}) string
+}
+
+// makerNoteInts converts a decoded SHORT/LONG array to ints
+// signed reinterprets 16-bit values as int16 (most vendors store
+// signed fields with the unsigned SHORT type)
+func makerNoteInts(raw interface{}, signed bool) []int {
+	var out []int
+	switch v := raw.(type) {
+	case []uint16:
+		for _, x := range v {
+			if signed {
+				out = append(out, int(int16(x)))
+			} else {
+				out = append(out, int(x))
+			}
+		}
+	case []byte:
+		for _, x := range v {
+			out = append(out, int(x))
+		}
+	default:
+		for _, x := range valueFloats(raw) {
+			out = append(out, int(x))
+		}
+	}
+	return out
+}
+
+// setArrayFields reports the listed elements of a packed array
+// skip reports values that mean "not applicable" for this vendor
+func (r *tiffReader) setArrayFields(group string, tag uint16, values []int, fields []arrayField, entryOffset int, skip func(v int) bool) {
+	for _, f := range fields {
+		if f.Index >= len(values) {
+			continue
+		}
+		v := values[f.Index]
+		if skip != nil && skip(v) {
+			continue
+		}
+		raw := []int32{int32(v)}
+		value := formatTagValue(raw)
+		if f.Format != nil {
+			value = f.Format(raw)
+		}
+		if value != "" {
+			r.set(group, tag, f.Name, raw, value, entryOffset)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Group names used by the parsers
+const (
+	GroupIFD0      = "IFD0"
+	GroupExif      = "ExifIFD"
+	GroupGPS       = "GPS"
+	GroupInterop   = "InteropIFD"
+	GroupIFD1      = "IFD1"
+	GroupXMP       = "XMP"
+	GroupIPTC      = "IPTC"
+	GroupICC       = "ICC"
+	GroupJPEG      = "JPEG"
+	GroupJFIF      = "JFIF"
+	GroupPhotoshop = "Photoshop"
+	GroupAdobe     = "Adobe"
+	GroupMPF       = "MPF"
+	GroupGainMap   = "GainMap"
+	GroupContainer = "Container"
+	GroupTrailer   = "Trailer"
+	GroupC2PA      = "C2PA"
+	GroupPNG       = "PNG"
+	GroupWebP      = "WebP"
+	GroupHEIF      = "HEIF"
+	GroupAVIF      = "AVIF"
+	GroupCanon     = "Canon"
+	GroupNikon     = "Nikon"
+	GroupSony      = "Sony"
+	GroupFujifilm  = "Fujifilm"
+	GroupOlympus   = "Olympus"
+	GroupPanasonic = "Panasonic"
+	GroupApple     = "Apple"
+	GroupDrone     = "Drone"
+	GroupErrors    = "Errors"
+)
+
+// Metadata is the structured result produced by every Parser
+// Groups keep the order in which they were first seen in the file
+type Metadata struct {
+	Format ImageFormat `json:"-"`
+	Groups []*Group    `json:"groups"`
+
+	// thumbnail is the embedded preview found in IFD1, if any
+	thumbnail *Thumbnail
+
+	// strips locates an uncompressed IFD1 thumbnail until it is built
+	strips *stripThumbnail
+
+	// mpImages are the secondary images listed by MPF
+	mpImages []mpImage
+
+	// gainMap is the HDR gain map image, if any
+	gainMap *GainMap
+
+	// media are the items stored after the primary image or in XMP
+	media []EmbeddedMedia
+}
+
+// Group holds the entries read from one IFD or container
+type Group struct {
+	Name    string   `json:"name"`
+	Entries []*Entry `json:"entries"`
+}
+
+// Entry is a single metadata value
+type Entry struct {
+	// TagID is the numeric tag for TIFF-based entries (nil for others)
+	TagID *uint16 `json:"tagId,omitempty"`
+
+	Name string `json:"name"`
+
+	// Raw is the decoded value before formatting (e.g. []uint16{6})
+	Raw interface{} `json:"raw,omitempty"`
+
+	// Value is the human-readable value (e.g. "Rotate 90 CW")
+	Value string `json:"value"`
+
+	// Offset is the absolute byte offset of the entry in the file (-1 if unknown)
+	Offset int `json:"offset"`
+
+	// Source names the segment, chunk or box the entry was read from
+	Source string `json:"source,omitempty"`
+}
+
+// NewMetadata creates an empty result for the given format
+func NewMetadata(format ImageFormat) *Metadata {
+	return &Metadata{Format: format}
+}
+
+// Group returns the named group, creating it if necessary
+func (m *Metadata) Group(name string) *Group {
+	for _, g := range m.Groups {
+		if g.Name == name {
+			return g
+		}
+	}
+	g := &Group{Name: name}
+	m.Groups = append(m.Groups, g)
+	return g
+}
+
+// Set stores a plain text entry in the named group
+func (m *Metadata) Set(group, name, value string, offset int, source string) *Entry {
+	return m.Group(group).Set(&Entry{Name: name, Value: value, Offset: offset, Source: source})
+}
+
+// SetRaw stores an entry that keeps its typed raw value next to the display text
+func (m *Metadata) SetRaw(group, name string, raw interface{}, value string, offset int, source string) *Entry {
+	return m.Group(group).Set(&Entry{Name: name, Raw: raw, Value: value, Offset: offset, Source: source})
+}
+
+// Get returns the named entry of a group, or nil
+func (m *Metadata) Get(group, name string) *Entry {
+	for _, g := range m.Groups {
+		if g.Name == group {
+			return g.Get(name)
+		}
+	}
+	return nil
+}
+
+// hasGroup reports whether the named group has been created
+func (m *Metadata) hasGroup(name string) bool {
+	for _, g := range m.Groups {
+		if g.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the total number of entries
+func (m *Metadata) Len() int {
+	n := 0
+	for _, g := range m.Groups {
+		n += len(g.Entries)
+	}
+	return n
+}
+
+// Set adds an entry, replacing any existing entry with the same name
+func (g *Group) Set(e *Entry) *Entry {
+	for i, existing := range g.Entries {
+		if existing.Name == e.Name {
+			g.Entries[i] = e
+			return e
+		}
+	}
+	g.Entries = append(g.Entries, e)
+	return e
+}
+
+// Get returns the named entry, or nil
+func (g *Group) Get(name string) *Entry {
+	for _, e := range g.Entries {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// maxJSONRawBytes is the largest binary raw value included in the JSON
+// encoding; larger blobs (MakerNote, ICC profiles, thumbnails) are left out
+const maxJSONRawBytes = 256
+
+// MarshalJSON encodes the entry with a raw value that encoding/json accepts:
+// large byte slices are dropped and NaN or infinite floats become strings
+func (e Entry) MarshalJSON() ([]byte, error) {
+	type plain Entry
+	out := plain(e)
+	out.Raw = jsonRaw(e.Raw)
+	return json.Marshal(out)
+}
+
+// jsonRaw returns raw in a form that encoding/json can marshal
+func jsonRaw(raw interface{}) interface{} {
+	switch v := raw.(type) {
+	case []byte:
+		if len(v) > maxJSONRawBytes {
+			return nil
+		}
+	case float32:
+		if !isFinite(float64(v)) {
+			return jsonFloat(float64(v))
+		}
+	case float64:
+		if !isFinite(v) {
+			return jsonFloat(v)
+		}
+	case []float32:
+		for _, f := range v {
+			if !isFinite(float64(f)) {
+				out := make([]interface{}, len(v))
+				for i, f := range v {
+					out[i] = f
+					if !isFinite(float64(f)) {
+						out[i] = jsonFloat(float64(f))
+					}
+				}
+				return out
+			}
+		}
+	case []float64:
+		for _, f := range v {
+			if !isFinite(f) {
+				out := make([]interface{}, len(v))
+				for i, f := range v {
+					out[i] = jsonFloat(f)
+				}
+				return out
+			}
+		}
+	}
+	return raw
+}
+
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// jsonFloat spells non-finite values as "NaN", "+Inf" or "-Inf"
+func jsonFloat(f float64) interface{} {
+	if isFinite(f) {
+		return f
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// flatPrefixes lists the groups whose entries are flattened without a
+// group prefix; all other groups are flattened as "<Group>_<Name>"
+var flatPrefixes = map[string]string{
+	GroupIFD0:   "",
+	GroupExif:   "",
+	GroupGPS:    "",
+	GroupErrors: "",
+}
+
+// legacyKeys maps flattened keys back to the names the JPEG segment
+// scanner used before entries were grouped, which the UI still matches on
+var legacyKeys = func() map[string]string {
+	keys := map[string]string{
+		GroupJPEG + "_FlashPix":       "FlashPix",
+		GroupJFIF + "_JFXX_Extension": "JFXX_Extension",
+	}
+	for n := 0; n <= 15; n++ {
+		name := fmt.Sprintf("APP%d_Data", n)
+		keys[GroupJPEG+"_"+name] = name
+	}
+	return keys
+}()
+
+// ExifData flattens the structured result into the legacy map used by the
+// extension UI. Interpreted single values also get a "<Name>_Raw" key.
+// When two groups produce the same key, the later one is prefixed with
+// its group name.
+func (m *Metadata) ExifData() ExifData {
+	exifData := make(ExifData)
+
+	for _, g := range m.Groups {
+		prefix, ok := flatPrefixes[g.Name]
+		if !ok {
+			prefix = g.Name + "_"
+		}
+
+		for _, e := range g.Entries {
+			key := prefix + e.Name
+			if legacy, ok := legacyKeys[key]; ok {
+				key = legacy
+			}
+			if _, exists := exifData[key]; exists {
+				key = g.Name + "_" + e.Name
+			}
+			exifData[key] = e.Value
+
+			if raw := formatTagValue(e.Raw); raw != "" && raw != e.Value && valueLen(e.Raw) == 1 {
+				if _, isText := e.Raw.(string); !isText {
+					exifData[key+"_Raw"] = raw
+				}
+			}
+		}
+	}
+
+	return exifData
+}
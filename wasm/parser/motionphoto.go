@@ -0,0 +1,196 @@
+package parser
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Google Motion Photos and depth photos
+// A Motion Photo appends an MP4 after the primary JPEG, described by
+// GCamera:MotionPhoto and a Container:Directory item (version 1) or by
+// GCamera:MicroVideoOffset, its distance from the end of the file (the
+// older MicroVideo format). Portrait shots store a depth map either as a
+// Container item or base64-encoded in GDepth:Data, usually in extended XMP.
+
+// Container:Directory semantics of the media returned by the Extract
+// functions
+const (
+	semanticMotionPhoto = "MotionPhoto"
+	semanticDepth       = "Depth"
+	semanticConfidence  = "Confidence"
+)
+
+// ExtractMotionPhoto parses the image and returns its Motion Photo video
+func ExtractMotionPhoto(data []byte) (*EmbeddedMedia, error) {
+	return ExtractEmbeddedMedia(data, semanticMotionPhoto)
+}
+
+// ExtractDepthMap parses the image and returns its depth map image
+func ExtractDepthMap(data []byte) (*EmbeddedMedia, error) {
+	return ExtractEmbeddedMedia(data, semanticDepth)
+}
+
+// reportContainerMedia reports the Container:Directory items, Motion
+// Photo video and depth map of a JPEG, keeping each located item for
+// extraction. end is the offset just past the primary image's EOI
+func reportContainerMedia(md *Metadata, file []byte, end int) {
+	items := readContainerDirectory(md)
+	locateContainerItems(items, end, len(file))
+	for i, item := range items {
+		value := item.Semantic
+		if item.Mime != "" {
+			value += ", " + item.Mime
+		}
+		switch {
+		case i == 0:
+			// The primary image is the JPEG itself
+		case item.Offset > 0 && item.Length <= len(file)-item.Offset:
+			value += fmt.Sprintf(", %d bytes at offset %d", item.Length, item.Offset)
+			md.media = append(md.media, EmbeddedMedia{
+				Semantic: item.Semantic,
+				MIMEType: item.Mime,
+				Offset:   item.Offset,
+				Data:     file[item.Offset : item.Offset+item.Length],
+			})
+		default:
+			value += fmt.Sprintf(", %d bytes (outside the file)", item.Length)
+		}
+		if item.Padding > 0 {
+			value += fmt.Sprintf(", padding %d", item.Padding)
+		}
+		md.Set(GroupContainer, fmt.Sprintf("Item%d", i+1), value, item.Offset, "XMP")
+	}
+
+	reportMotionPhoto(md, file)
+	reportDepthMap(md)
+}
+
+// reportMotionPhoto summarises the Motion Photo video. Files written
+// before the Container format only give MicroVideoOffset
+func reportMotionPhoto(md *Metadata, file []byte) {
+	video := findMedia(md, semanticMotionPhoto)
+	version := xmpValue(md, "GCamera:MotionPhotoVersion")
+	timestamp := xmpValue(md, "GCamera:MotionPhotoPresentationTimestampUs")
+
+	if video == nil && xmpValue(md, "GCamera:MicroVideo") == "1" {
+		version = xmpValue(md, "GCamera:MicroVideoVersion")
+		timestamp = xmpValue(md, "GCamera:MicroVideoPresentationTimestampUs")
+		size, err := strconv.Atoi(xmpValue(md, "GCamera:MicroVideoOffset"))
+		if err != nil || size <= 0 || size > len(file) {
+			md.Set(GroupErrors, "MotionPhoto_ParseError", "invalid GCamera:MicroVideoOffset", -1, "XMP")
+			return
+		}
+		md.media = append(md.media, EmbeddedMedia{
+			Semantic: semanticMotionPhoto,
+			MIMEType: "video/mp4",
+			Offset:   len(file) - size,
+			Data:     file[len(file)-size:],
+		})
+		video = &md.media[len(md.media)-1]
+	}
+	if video == nil {
+		if xmpValue(md, "GCamera:MotionPhoto") == "1" {
+			md.Set(GroupContainer, "MotionPhoto", "declared, video not found", -1, "XMP")
+		}
+		return
+	}
+
+	value := fmt.Sprintf("%s, %d bytes", video.MIMEType, len(video.Data))
+	if !isMP4(video.Data) {
+		value += " (not an MP4 file)"
+	}
+	if version != "" {
+		value += ", version " + version
+	}
+	// -1 means the writer did not specify a presentation frame
+	if us, err := strconv.ParseInt(timestamp, 10, 64); err == nil && us >= 0 {
+		value += ", still frame at " + formatDecimal(float64(us)/1e6, 3) + " s"
+	}
+	md.Set(GroupContainer, "MotionPhoto", value, video.Offset, "XMP")
+}
+
+// reportDepthMap summarises the depth map, taking it from GDepth:Data
+// when no Container item holds one
+func reportDepthMap(md *Metadata) {
+	depth := findMedia(md, semanticDepth)
+	if depth == nil {
+		for _, field := range []struct{ semantic, data, mime string }{
+			{semanticDepth, "GDepth:Data", "GDepth:Mime"},
+			{semanticConfidence, "GDepth:Confidence", "GDepth:ConfidenceMime"},
+		} {
+			encoded := xmpValue(md, field.data)
+			if encoded == "" {
+				continue
+			}
+			data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+			if err != nil {
+				md.Set(GroupErrors, "DepthMap_ParseError", field.data+": "+err.Error(), -1, "XMP")
+				continue
+			}
+			md.media = append(md.media, EmbeddedMedia{
+				Semantic: field.semantic,
+				MIMEType: xmpValue(md, field.mime),
+				Offset:   -1,
+				Data:     data,
+			})
+		}
+		depth = findMedia(md, semanticDepth)
+	}
+	if depth == nil {
+		return
+	}
+
+	value := depth.MIMEType
+	if value == "" {
+		value = "image"
+	}
+	if dims := embeddedImageSize(depth.Data); dims != "" {
+		value += " " + dims
+	}
+	value += fmt.Sprintf(", %d bytes", len(depth.Data))
+	if format := xmpValue(md, "GDepth:Format"); format != "" {
+		value += ", " + format
+	}
+	near, far := xmpValue(md, "GDepth:Near"), xmpValue(md, "GDepth:Far")
+	if near != "" && far != "" {
+		units := xmpValue(md, "GDepth:Units")
+		if units == "" {
+			units = "m"
+		}
+		value += fmt.Sprintf(", %s-%s %s", near, far, units)
+	}
+	md.Set(GroupContainer, "DepthMap", value, depth.Offset, "XMP")
+}
+
+// findMedia returns the first media item with the given semantic
+func findMedia(md *Metadata, semantic string) *EmbeddedMedia {
+	for i := range md.media {
+		if md.media[i].Semantic == semantic {
+			return &md.media[i]
+		}
+	}
+	return nil
+}
+
+// embeddedImageSize returns the dimensions of a JPEG or PNG as "WxH"
+func embeddedImageSize(data []byte) string {
+	switch DetectFormat(data) {
+	case FormatJPEG:
+		if sub, err := (&SimpleExifParser{nested: true}).Parse(data); err == nil {
+			return frameSize(sub)
+		}
+	case FormatPNG:
+		if len(data) >= 24 && string(data[12:16]) == "IHDR" {
+			return fmt.Sprintf("%dx%d", binary.BigEndian.Uint32(data[16:20]), binary.BigEndian.Uint32(data[20:24]))
+		}
+	}
+	return ""
+}
+
+// isMP4 reports whether data starts with an ISOBMFF ftyp box
+func isMP4(data []byte) bool {
+	return len(data) >= 12 && string(data[4:8]) == "ftyp"
+}
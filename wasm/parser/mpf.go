@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Multi-Picture Format (CIPA DC-007) APP2 segments
+// The signature is followed by a TIFF header (the MP header). The first
+// image carries the MP Index IFD listing every image in the file, then
+// its own MP Attribute IFD; the other images only carry an attribute IFD.
+// Image offsets are relative to the MP header of the first image.
+const mpfSignature = "MPF\x00"
+
+// mpTagEntry holds the 16-byte MP entry of each image
+const mpTagEntry = 0xB002
+
+// mpfTags covers the MP Index and MP Attribute IFDs, whose tag IDs do
+// not overlap
+var mpfTags = tagTable{
+	0xB000: {Name: "MPFVersion", Type: typeUndefined, Count: 4, Format: formatVersion},
+	0xB001: {Name: "NumberOfImages", Type: typeLong, Count: 1},
+	0xB004: {Name: "TotalFrames", Type: typeLong, Count: 1},
+	0xB101: {Name: "MPIndividualNum", Type: typeLong, Count: 1},
+	0xB201: {Name: "PanOrientation", Type: typeLong, Count: 1, Format: formatHex32},
+	0xB202: {Name: "PanOverlapH", Type: typeRational, Count: 1, Format: formatUnit("%", 1)},
+	0xB203: {Name: "PanOverlapV", Type: typeRational, Count: 1, Format: formatUnit("%", 1)},
+	0xB204: {Name: "BaseViewpointNum", Type: typeLong, Count: 1},
+	0xB205: {Name: "ConvergenceAngle", Type: typeSRational, Count: 1, Format: formatUnit("deg", 2)},
+	0xB206: {Name: "BaselineLength", Type: typeRational, Count: 1, Format: formatUnit("m", 3)},
+	0xB207: {Name: "VerticalDivergence", Type: typeSRational, Count: 1, Format: formatUnit("deg", 2)},
+	0xB208: {Name: "AxisDistanceX", Type: typeSRational, Count: 1, Format: formatUnit("m", 3)},
+	0xB209: {Name: "AxisDistanceY", Type: typeSRational, Count: 1, Format: formatUnit("m", 3)},
+	0xB20A: {Name: "AxisDistanceZ", Type: typeSRational, Count: 1, Format: formatUnit("m", 3)},
+	0xB20B: {Name: "YawAngle", Type: typeSRational, Count: 1, Format: formatUnit("deg", 2)},
+	0xB20C: {Name: "PitchAngle", Type: typeSRational, Count: 1, Format: formatUnit("deg", 2)},
+	0xB20D: {Name: "RollAngle", Type: typeSRational, Count: 1, Format: formatUnit("deg", 2)},
+}
+
+// mpImageTypes names the type code in the low 24 bits of an MP entry
+var mpImageTypes = map[int]string{
+	0x000000: "Undefined",
+	0x010001: "Large Thumbnail (VGA equivalent)",
+	0x010002: "Large Thumbnail (Full HD equivalent)",
+	0x010003: "Large Thumbnail (4K equivalent)",
+	0x010004: "Large Thumbnail (8K equivalent)",
+	0x010005: "Large Thumbnail (16K equivalent)",
+	0x020001: "Multi-frame Panorama",
+	0x020002: "Multi-frame Disparity",
+	0x020003: "Multi-angle",
+	0x030000: "Baseline MP Primary Image",
+	0x040000: "Original Preservation Image",
+	0x050000: "Gain Map Image",
+}
+
+// mpTypeGainMap is the type code of ISO 21496-1 gain map images
+const mpTypeGainMap = 0x050000
+
+// mpImage is a secondary image listed in the MP Index
+type mpImage struct {
+	index     int
+	imageType int
+	offset    int
+	data      []byte
+
+	// md holds the metadata of the image itself
+	md *Metadata
+}
+
+// mpImageFlags names the top bits of an MP entry's attribute
+var mpImageFlags = []struct {
+	bit  uint32
+	name string
+}{
+	{1 << 31, "Dependent parent"},
+	{1 << 30, "Dependent child"},
+	{1 << 29, "Representative image"},
+}
+
+// parseMPF reports an MP header and, for the first image, each entry of
+// the MP Index with the EXIF of the image it points to
+// file is the whole JPEG file; header starts at the MP header
+func (p *SimpleExifParser) parseMPF(md *Metadata, file, header []byte, headerOffset int, source string) {
+	byteOrder, ifdOffset, ok := tiffHeader(header)
+	if !ok {
+		md.Set(GroupErrors, "MPF_ParseError", "invalid MP header", headerOffset, source)
+		return
+	}
+	r := &tiffReader{data: header, byteOrder: byteOrder, base: headerOffset, source: source, md: md}
+
+	var entries []byte
+	entriesOffset := 0
+	expand := func(tag uint16, raw interface{}, entryOffset int) bool {
+		if tag != mpTagEntry {
+			return false
+		}
+		entries, _ = raw.([]byte)
+		entriesOffset = entryOffset
+		return true
+	}
+	p.parseMakerNoteIFD(r, ifdOffset, GroupMPF, mpfTags, expand)
+
+	// The MP Attribute IFD follows the MP Index IFD
+	if ifdOffset >= 0 && ifdOffset+2 <= len(header) {
+		nextOffset := ifdOffset + 2 + int(byteOrder.Uint16(header[ifdOffset:]))*12
+		if nextOffset+4 <= len(header) {
+			if next := int(byteOrder.Uint32(header[nextOffset:])); next != 0 && next != ifdOffset {
+				p.parseMakerNoteIFD(r, next, GroupMPF, mpfTags, nil)
+			}
+		}
+	}
+
+	// Images embedded in the file are not followed again
+	if p.nested {
+		return
+	}
+	for i := 0; i+16 <= len(entries); i += 16 {
+		p.reportMPImage(r, file, entries[i:i+16], i/16+1, entriesOffset)
+	}
+}
+
+// reportMPImage reports the MP entry of image n (1-based): its type,
+// flags, size and location, then the metadata of the image itself
+// The primary image's entry has offset 0 and starts the file
+func (p *SimpleExifParser) reportMPImage(r *tiffReader, file, entry []byte, n, entryOffset int) {
+	byteOrder := r.byteOrder
+	attribute := byteOrder.Uint32(entry[0:4])
+	size := int(byteOrder.Uint32(entry[4:8]))
+	start := int(byteOrder.Uint32(entry[8:12]))
+	dependent1, dependent2 := byteOrder.Uint16(entry[12:14]), byteOrder.Uint16(entry[14:16])
+
+	group := fmt.Sprintf("MPImage%d", n)
+	imageType := lookupCode(mpImageTypes, int(attribute&0xFFFFFF))
+	set := func(name string, raw interface{}, value string) {
+		r.set(group, mpTagEntry, name, raw, value, entryOffset)
+	}
+
+	var flags []string
+	for _, f := range mpImageFlags {
+		if attribute&f.bit != 0 {
+			flags = append(flags, f.name)
+		}
+	}
+	if len(flags) > 0 {
+		set("MPImageFlags", []uint32{attribute >> 29}, strings.Join(flags, ", "))
+	}
+	format := "JPEG"
+	if attribute>>24&0x07 != 0 {
+		format = fmt.Sprintf("Unknown (%d)", attribute>>24&0x07)
+	}
+	set("MPImageFormat", []uint32{attribute >> 24 & 0x07}, format)
+	set("MPImageType", []uint32{attribute & 0xFFFFFF}, imageType)
+	set("MPImageLength", []uint32{uint32(size)}, fmt.Sprintf("%d bytes", size))
+	if dependent1 != 0 || dependent2 != 0 {
+		set("DependentImage1EntryNumber", []uint16{dependent1}, fmt.Sprintf("%d", dependent1))
+		set("DependentImage2EntryNumber", []uint16{dependent2}, fmt.Sprintf("%d", dependent2))
+	}
+
+	// Offsets are relative to the MP header, except the primary image's
+	absolute := 0
+	if start != 0 {
+		absolute = r.base + start
+		set("MPImageStart", []uint32{uint32(start)}, fmt.Sprintf("%d (file offset %d)", start, absolute))
+	} else {
+		set("MPImageStart", []uint32{0}, "0")
+	}
+	summary := imageType
+	switch {
+	case n == 1:
+		if dims := frameSize(r.md); dims != "" {
+			summary += " " + dims
+		}
+	case size <= 0 || start <= 0 || absolute+size > len(file) || absolute+size < absolute:
+		summary += " (outside the file)"
+	case size < 4 || file[absolute] != 0xFF || file[absolute+1] != 0xD8:
+		summary += " (not a JPEG)"
+	default:
+		image := file[absolute : absolute+size]
+		sub, err := p.reportEmbeddedJPEG(r.md, group, image, absolute)
+		if err != nil {
+			r.md.Set(GroupErrors, "MPF_ParseError", fmt.Sprintf("image %d: %v", n, err), absolute, r.source)
+			break
+		}
+		if dims := frameSize(sub); dims != "" {
+			summary += " " + dims
+		}
+		r.md.mpImages = append(r.md.mpImages, mpImage{
+			index:     n,
+			imageType: int(attribute & 0xFFFFFF),
+			offset:    absolute,
+			data:      image,
+			md:        sub,
+		})
+	}
+	r.md.Set(GroupMPF, fmt.Sprintf("Image%d", n), summary, absolute, r.source)
+}
+
+// reportEmbeddedJPEG parses an image stored inside the file and copies
+// its metadata into group, naming each entry after its original group
+// (e.g. "ExifIFD:PixelXDimension"). It returns the image's own metadata
+func (p *SimpleExifParser) reportEmbeddedJPEG(md *Metadata, group string, image []byte, offset int) (*Metadata, error) {
+	sub, err := (&SimpleExifParser{nested: true}).Parse(image)
+	if err != nil {
+		return nil, err
+	}
+
+	target := md.Group(group)
+	for _, g := range sub.Groups {
+		for _, e := range g.Entries {
+			copied := *e
+			copied.Name = g.Name + ":" + e.Name
+			if copied.Offset >= 0 {
+				copied.Offset += offset
+			}
+			target.Set(&copied)
+		}
+	}
+	return sub, nil
+}
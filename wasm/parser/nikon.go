@@ -0,0 +1,353 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Nikon MakerNote tags
+// Type 3 notes start with "Nikon\0", a version and a TIFF header of their
+// own; all offsets inside are relative to that inner header
+const (
+	nikonWhiteBalanceFineTune = 0x000B
+	nikonSerialNumber         = 0x001D
+	nikonVRInfo               = 0x001F
+	nikonShotInfo             = 0x0091
+	nikonLensData             = 0x0098
+	nikonShutterCount         = 0x00A7
+)
+
+var nikonTags = tagTable{
+	0x0001: {Name: "MakerNoteVersion", Type: typeUndefined, Count: 4, Format: formatVersion},
+	0x0002: {Name: "ISO", Type: typeShort, Count: 2, Format: formatNikonISO},
+	0x0004: {Name: "Quality", Type: typeASCII},
+	0x0005: {Name: "WhiteBalance", Type: typeASCII},
+	0x0007: {Name: "FocusMode", Type: typeASCII},
+	0x0008: {Name: "FlashSetting", Type: typeASCII},
+	0x000B: {Name: "WhiteBalanceFineTune", Type: typeSShort},
+	0x001D: {Name: "SerialNumber", Type: typeASCII},
+	0x001E: {Name: "ColorSpace", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{1: "sRGB", 2: "Adobe RGB"})},
+	0x0022: {Name: "ActiveD-Lighting", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		0: "Off", 1: "Low", 3: "Normal", 5: "High", 7: "Extra High",
+		8: "Extra High 1", 9: "Extra High 2", 10: "Extra High 3", 11: "Extra High 4", 0xFFFF: "Auto",
+	})},
+	0x0083: {Name: "LensType", Type: typeByte, Count: 1, Format: formatNikonLensType},
+	0x0084: {Name: "Lens", Type: typeRational, Count: 4, Format: formatLensSpecification},
+	0x0086: {Name: "DigitalZoom", Type: typeRational, Count: 1},
+	0x0093: {Name: "NEFCompression", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		1: "Lossy (type 1)", 2: "Uncompressed", 3: "Lossless", 4: "Lossy (type 2)",
+		5: "Striped packed 12 bits", 6: "Uncompressed (reduced to 12 bit)", 7: "Unpacked 12 bits",
+		8: "Small", 9: "Packed 12 bits", 10: "Packed 14 bits", 13: "High Efficiency", 14: "High Efficiency*",
+	})},
+	0x0095: {Name: "NoiseReduction", Type: typeASCII},
+	0x00A7: {Name: "ShutterCount", Type: typeLong, Count: 1},
+	0x00AB: {Name: "VariProgram", Type: typeASCII},
+	0x00B1: {Name: "HighISONoiseReduction", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		0: "Off", 1: "Minimal", 2: "Low", 3: "Medium Low", 4: "Normal", 5: "Medium High", 6: "High",
+	})},
+}
+
+// nikonVRInfoFields lists the VRInfo (0x001F) fields after the 4-byte version
+var nikonVRInfoFields = []arrayField{
+	{4, "VibrationReduction", formatEnum(map[int]string{0: "n/a", 1: "On", 2: "Off"})},
+	{6, "VRMode", formatEnum(map[int]string{0: "Normal", 1: "On (1)", 2: "Active", 3: "Sport"})},
+}
+
+// nikonLensDataFields maps LensData versions to their layout
+// 0201-0204 are stored encrypted; 04xx and later (Z mount) use a
+// different layout and only their version is reported
+var nikonLensDataFields = map[string][]arrayField{
+	"0100": {
+		{6, "LensIDNumber", nil},
+		{7, "LensFStops", formatNikonFStops},
+		{8, "MinFocalLength", formatNikonFocalLength},
+		{9, "MaxFocalLength", formatNikonFocalLength},
+		{10, "MaxApertureAtMinFocal", formatNikonAperture},
+		{11, "MaxApertureAtMaxFocal", formatNikonAperture},
+		{12, "MCUVersion", nil},
+	},
+	"0101": nikonLensData0101,
+	"0201": nikonLensData0101,
+	"0202": nikonLensData0101,
+	"0203": nikonLensData0101,
+	"0204": {
+		{4, "ExitPupilPosition", formatNikonExitPupil},
+		{5, "AFAperture", formatNikonAperture},
+		{10, "FocusDistance", formatNikonFocusDistance},
+		{11, "FocalLength", formatNikonFocalLength},
+		{12, "LensIDNumber", nil},
+		{13, "LensFStops", formatNikonFStops},
+		{14, "MinFocalLength", formatNikonFocalLength},
+		{15, "MaxFocalLength", formatNikonFocalLength},
+		{16, "MaxApertureAtMinFocal", formatNikonAperture},
+		{17, "MaxApertureAtMaxFocal", formatNikonAperture},
+		{18, "MCUVersion", nil},
+		{19, "EffectiveMaxAperture", formatNikonAperture},
+	},
+}
+
+var nikonLensData0101 = []arrayField{
+	{4, "ExitPupilPosition", formatNikonExitPupil},
+	{5, "AFAperture", formatNikonAperture},
+	{9, "FocusDistance", formatNikonFocusDistance},
+	{10, "FocalLength", formatNikonFocalLength},
+	{11, "LensIDNumber", nil},
+	{12, "LensFStops", formatNikonFStops},
+	{13, "MinFocalLength", formatNikonFocalLength},
+	{14, "MaxFocalLength", formatNikonFocalLength},
+	{15, "MaxApertureAtMinFocal", formatNikonAperture},
+	{16, "MaxApertureAtMaxFocal", formatNikonAperture},
+	{17, "MCUVersion", nil},
+	{18, "EffectiveMaxAperture", formatNikonAperture},
+}
+
+// nikonBlock is a binary MakerNote block kept until the decryption key
+// (serial number and shutter count) has been read
+type nikonBlock struct {
+	data        []byte
+	entryOffset int
+}
+
+// parseNikonMakerNote decodes a Nikon MakerNote
+func parseNikonMakerNote(p *SimpleExifParser, r *tiffReader, offset, size int) {
+	note := r.data[offset : offset+size]
+
+	if len(note) < 6 || string(note[:6]) != "Nikon\x00" {
+		// Early bodies (D1 series) store a plain IFD relative to the EXIF header
+		parseNikonIFD(p, r, offset)
+		return
+	}
+
+	// Type 1 notes ("Nikon\0\x01", Coolpix) use a different tag set
+	if len(note) < 18 || note[6] != 2 {
+		return
+	}
+	byteOrder, ifdOffset, ok := tiffHeader(note[10:])
+	if !ok {
+		return
+	}
+	parseNikonIFD(p, r.embedded(offset+10, offset+size, byteOrder), ifdOffset)
+}
+
+// parseNikonIFD reports the Nikon IFD at offset, then decrypts ShotInfo
+// and LensData once the serial number and shutter count are known
+func parseNikonIFD(p *SimpleExifParser, r *tiffReader, offset int) {
+	var shotInfo, lensData *nikonBlock
+	var serial string
+	var shutterCount uint32
+	haveCount := false
+
+	p.parseMakerNoteIFD(r, offset, GroupNikon, nikonTags, func(tag uint16, raw interface{}, entryOffset int) bool {
+		switch tag {
+		case nikonShotInfo, nikonLensData:
+			data, ok := raw.([]byte)
+			if !ok || len(data) < 4 {
+				return true
+			}
+			block := &nikonBlock{data: append([]byte(nil), data...), entryOffset: entryOffset}
+			if tag == nikonShotInfo {
+				shotInfo = block
+			} else {
+				lensData = block
+			}
+			return true
+		case nikonVRInfo:
+			r.setArrayFields(GroupNikon, tag, makerNoteInts(raw, false), nikonVRInfoFields, entryOffset, nil)
+			return true
+		case nikonWhiteBalanceFineTune:
+			values := makerNoteInts(raw, true)
+			text := make([]string, len(values))
+			for i, v := range values {
+				text[i] = strconv.Itoa(v)
+			}
+			r.set(GroupNikon, tag, "WhiteBalanceFineTune", raw, strings.Join(text, ", "), entryOffset)
+			return true
+		case nikonSerialNumber:
+			serial = valueString(raw)
+		case nikonShutterCount:
+			if n, ok := firstInt(raw); ok {
+				shutterCount, haveCount = uint32(n), true
+			}
+		}
+		return false
+	})
+
+	// Versions from 0200 on are encrypted after the 4-byte version
+	key := nikonSerialKey(serial, makerNoteModel(r))
+	decrypt := func(block *nikonBlock, tag uint16, name string) bool {
+		version := string(block.data[:4])
+		r.set(GroupNikon, tag, name, version, formatVersion([]byte(version)), block.entryOffset)
+		if version < "0200" {
+			return true
+		}
+		if !haveCount {
+			return false
+		}
+		nikonDecrypt(block.data[4:], key, shutterCount)
+		return true
+	}
+
+	if lensData != nil && decrypt(lensData, nikonLensData, "LensDataVersion") {
+		if fields, ok := nikonLensDataFields[string(lensData.data[:4])]; ok {
+			r.setArrayFields(GroupNikon, nikonLensData, makerNoteInts(lensData.data, false), fields, lensData.entryOffset, nil)
+		}
+	}
+	if shotInfo != nil && decrypt(shotInfo, nikonShotInfo, "ShotInfoVersion") {
+		reportNikonShotInfo(r, shotInfo)
+	}
+}
+
+// reportNikonShotInfo reports the firmware version that most bodies
+// store right after the ShotInfo version; the rest of ShotInfo has a
+// different layout for every body
+func reportNikonShotInfo(r *tiffReader, block *nikonBlock) {
+	// e.g. "1.10" padded with NULs or spaces
+	if len(block.data) < 9 {
+		return
+	}
+	firmware := valueString(block.data[4:9])
+	if len(firmware) >= 3 && strings.Trim(firmware, "0123456789.") == "" && strings.Contains(firmware, ".") {
+		r.set(GroupNikon, nikonShotInfo, "FirmwareVersion", firmware, firmware, block.entryOffset)
+	}
+}
+
+// nikonSerialKey derives the first decryption key byte from the serial
+// number; bodies with non-numeric serials use fixed keys
+func nikonSerialKey(serial, model string) uint8 {
+	if n, err := strconv.ParseUint(serial, 10, 64); err == nil {
+		return uint8(n)
+	}
+	if strings.HasSuffix(model, "D50") {
+		return 0x22
+	}
+	return 0x60
+}
+
+// nikonDecrypt decrypts ShotInfo/LensData in place
+// The keystream is seeded from the serial number and the XOR of the
+// shutter count bytes through two substitution tables
+func nikonDecrypt(data []byte, serialKey uint8, shutterCount uint32) {
+	countKey := uint8(shutterCount) ^ uint8(shutterCount>>8) ^ uint8(shutterCount>>16) ^ uint8(shutterCount>>24)
+	ci := nikonXlat[0][serialKey]
+	cj := nikonXlat[1][countKey]
+	ck := uint8(0x60)
+	for i := range data {
+		cj += ci * ck
+		ck++
+		data[i] ^= cj
+	}
+}
+
+// nikonXlat are the substitution tables of the Nikon MakerNote cipher
+var nikonXlat = [2][256]uint8{
+	{
+		0xc1, 0xbf, 0x6d, 0x0d, 0x59, 0xc5, 0x13, 0x9d, 0x83, 0x61, 0x6b, 0x4f, 0xc7, 0x7f, 0x3d, 0x3d,
+		0x53, 0x59, 0xe3, 0xc7, 0xe9, 0x2f, 0x95, 0xa7, 0x95, 0x1f, 0xdf, 0x7f, 0x2b, 0x29, 0xc7, 0x0d,
+		0xdf, 0x07, 0xef, 0x71, 0x89, 0x3d, 0x13, 0x3d, 0x3b, 0x13, 0xfb, 0x0d, 0x89, 0xc1, 0x65, 0x1f,
+		0xb3, 0x0d, 0x6b, 0x29, 0xe3, 0xfb, 0xef, 0xa3, 0x6b, 0x47, 0x7f, 0x95, 0x35, 0xa7, 0x47, 0x4f,
+		0xc7, 0xf1, 0x59, 0x95, 0x35, 0x11, 0x29, 0x61, 0xf1, 0x3d, 0xb3, 0x2b, 0x0d, 0x43, 0x89, 0xc1,
+		0x9d, 0x9d, 0x89, 0x65, 0xf1, 0xe9, 0xdf, 0xbf, 0x3d, 0x7f, 0x53, 0x97, 0xe5, 0xe9, 0x95, 0x17,
+		0x1d, 0x3d, 0x8b, 0xfb, 0xc7, 0xe3, 0x67, 0xa7, 0x07, 0xf1, 0x71, 0xa7, 0x53, 0xb5, 0x29, 0x89,
+		0xe5, 0x2b, 0xa7, 0x17, 0x29, 0xe9, 0x4f, 0xc5, 0x65, 0x6d, 0x6b, 0xef, 0x0d, 0x89, 0x49, 0x2f,
+		0xb3, 0x43, 0x53, 0x65, 0x1d, 0x49, 0xa3, 0x13, 0x89, 0x59, 0xef, 0x6b, 0xef, 0x65, 0x1d, 0x0b,
+		0x59, 0x13, 0xe3, 0x4f, 0x9d, 0xb3, 0x29, 0x43, 0x2b, 0x07, 0x1d, 0x95, 0x59, 0x59, 0x47, 0xfb,
+		0xe5, 0xe9, 0x61, 0x47, 0x2f, 0x35, 0x7f, 0x17, 0x7f, 0xef, 0x7f, 0x95, 0x95, 0x71, 0xd3, 0xa3,
+		0x0b, 0x71, 0xa3, 0xad, 0x0b, 0x3b, 0xb5, 0xfb, 0xa3, 0xbf, 0x4f, 0x83, 0x1d, 0xad, 0xe9, 0x2f,
+		0x71, 0x65, 0xa3, 0xe5, 0x07, 0x35, 0x3d, 0x0d, 0xb5, 0xe9, 0xe5, 0x47, 0x3b, 0x9d, 0xef, 0x35,
+		0xa3, 0xbf, 0xb3, 0xdf, 0x53, 0xd3, 0x97, 0x53, 0x49, 0x71, 0x07, 0x35, 0x61, 0x71, 0x2f, 0x43,
+		0x2f, 0x11, 0xdf, 0x17, 0x97, 0xfb, 0x95, 0x3b, 0x7f, 0x6b, 0xd3, 0x25, 0xbf, 0xad, 0xc7, 0xc5,
+		0xc5, 0xb5, 0x8b, 0xef, 0x2f, 0xd3, 0x07, 0x6b, 0x25, 0x49, 0x95, 0x25, 0x49, 0x6d, 0x71, 0xc7,
+	},
+	{
+		0xa7, 0xbc, 0xc9, 0xad, 0x91, 0xdf, 0x85, 0xe5, 0xd4, 0x78, 0xd5, 0x17, 0x46, 0x7c, 0x29, 0x4c,
+		0x4d, 0x03, 0xe9, 0x25, 0x68, 0x11, 0x86, 0xb3, 0xbd, 0xf7, 0x6f, 0x61, 0x22, 0xa2, 0x26, 0x34,
+		0x2a, 0xbe, 0x1e, 0x46, 0x14, 0x68, 0x9d, 0x44, 0x18, 0xc2, 0x40, 0xf4, 0x7e, 0x5f, 0x1b, 0xad,
+		0x0b, 0x94, 0xb6, 0x67, 0xb4, 0x0b, 0xe1, 0xea, 0x95, 0x9c, 0x66, 0xdc, 0xe7, 0x5d, 0x6c, 0x05,
+		0xda, 0xd5, 0xdf, 0x7a, 0xef, 0xf6, 0xdb, 0x1f, 0x82, 0x4c, 0xc0, 0x68, 0x47, 0xa1, 0xbd, 0xee,
+		0x39, 0x50, 0x56, 0x4a, 0xdd, 0xdf, 0xa5, 0xf8, 0xc6, 0xda, 0xca, 0x90, 0xca, 0x01, 0x42, 0x9d,
+		0x8b, 0x0c, 0x73, 0x43, 0x75, 0x05, 0x94, 0xde, 0x24, 0xb3, 0x80, 0x34, 0xe5, 0x2c, 0xdc, 0x9b,
+		0x3f, 0xca, 0x33, 0x45, 0xd0, 0xdb, 0x5f, 0xf5, 0x52, 0xc3, 0x21, 0xda, 0xe2, 0x22, 0x72, 0x6b,
+		0x3e, 0xd0, 0x5b, 0xa8, 0x87, 0x8c, 0x06, 0x5d, 0x0f, 0xdd, 0x09, 0x19, 0x93, 0xd0, 0xb9, 0xfc,
+		0x8b, 0x0f, 0x84, 0x60, 0x33, 0x1c, 0x9b, 0x45, 0xf1, 0xf0, 0xa3, 0x94, 0x3a, 0x12, 0x77, 0x33,
+		0x4d, 0x44, 0x78, 0x28, 0x3c, 0x9e, 0xfd, 0x65, 0x57, 0x16, 0x94, 0x6b, 0xfb, 0x59, 0xd0, 0xc8,
+		0x22, 0x36, 0xdb, 0xd2, 0x63, 0x98, 0x43, 0xa1, 0x04, 0x87, 0x86, 0xf7, 0xa6, 0x26, 0xbb, 0xd6,
+		0x59, 0x4d, 0xbf, 0x6a, 0x2e, 0xaa, 0x2b, 0xef, 0xe6, 0x78, 0xb6, 0x4e, 0xe0, 0x2f, 0xdc, 0x7c,
+		0xbe, 0x57, 0x19, 0x32, 0x7e, 0x2a, 0xd0, 0xb8, 0xba, 0x29, 0x00, 0x3c, 0x52, 0x7d, 0xa8, 0x49,
+		0x3b, 0x2d, 0xeb, 0x25, 0x49, 0xfa, 0xa3, 0xaa, 0x39, 0xa7, 0xc5, 0xa7, 0x50, 0x11, 0x36, 0xfb,
+		0xc6, 0x67, 0x4a, 0xf5, 0xa5, 0x12, 0x65, 0x7e, 0xb0, 0xdf, 0xaf, 0x4e, 0xb3, 0x61, 0x7f, 0x2f,
+	},
+}
+
+func formatNikonISO(value interface{}) string {
+	values := valueFloats(value)
+	for i := len(values) - 1; i >= 0; i-- {
+		if values[i] > 0 {
+			return fmt.Sprintf("%d", int(values[i]))
+		}
+	}
+	return ""
+}
+
+// nikonLensTypeBits names the LensType (0x0083) flags
+var nikonLensTypeBits = []string{"MF", "D", "G", "VR", "1", "FT-1", "E", "AF-P"}
+
+func formatNikonLensType(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok {
+		return ""
+	}
+	var flags []string
+	for i, name := range nikonLensTypeBits {
+		if v&(1<<uint(i)) != 0 {
+			flags = append(flags, name)
+		}
+	}
+	if len(flags) == 0 {
+		return "(none)"
+	}
+	return strings.Join(flags, " ")
+}
+
+// formatNikonFocalLength and the following LensData formatters decode
+// logarithmic byte codes
+func formatNikonFocalLength(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok || v == 0 {
+		return ""
+	}
+	return formatDecimal(5*math.Exp2(float64(v)/24), 1) + " mm"
+}
+
+func formatNikonAperture(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok || v == 0 {
+		return ""
+	}
+	return "f/" + formatDecimal(math.Exp2(float64(v)/24), 1)
+}
+
+func formatNikonFocusDistance(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok || v == 0 {
+		return ""
+	}
+	return formatDecimal(0.01*math.Pow(10, float64(v)/40), 2) + " m"
+}
+
+func formatNikonFStops(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok {
+		return ""
+	}
+	return formatDecimal(float64(v)/12, 2)
+}
+
+func formatNikonExitPupil(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok || v == 0 {
+		return ""
+	}
+	return formatDecimal(2048/float64(v), 1) + " mm"
+}
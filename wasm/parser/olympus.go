@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Olympus / OM System MakerNote tags
+// Current bodies start the note with "OLYMPUS\0" + byte order + version
+// (12 bytes) or "OM SYSTEM\0\0\0" + byte order + version (16 bytes), with
+// offsets relative to the start of the note. Older bodies use "OLYMP\0"
+// (8 bytes) with offsets relative to the TIFF header.
+const (
+	olympusEquipment      = 0x2010
+	olympusCameraSettings = 0x2020
+)
+
+var olympusTags = tagTable{
+	0x0000: {Name: "MakerNoteVersion", Type: typeUndefined, Count: 4, Format: formatVersion},
+	0x0207: {Name: "CameraType", Type: typeASCII},
+}
+
+var olympusEquipmentTags = tagTable{
+	0x0100: {Name: "CameraType2", Type: typeASCII},
+	0x0101: {Name: "SerialNumber", Type: typeASCII},
+	0x0102: {Name: "InternalSerialNumber", Type: typeASCII},
+	0x0104: {Name: "BodyFirmwareVersion", Type: typeLong, Count: 1, Format: formatOlympusFirmware},
+	0x0202: {Name: "LensSerialNumber", Type: typeASCII},
+	0x0203: {Name: "LensModel", Type: typeASCII},
+	0x0204: {Name: "LensFirmwareVersion", Type: typeLong, Count: 1, Format: formatOlympusFirmware},
+	0x0205: {Name: "MaxApertureAtMinFocal", Type: typeShort, Count: 1, Format: formatOlympusAperture},
+	0x0206: {Name: "MaxApertureAtMaxFocal", Type: typeShort, Count: 1, Format: formatOlympusAperture},
+	0x0207: {Name: "MinFocalLength", Type: typeShort, Count: 1, Format: formatUnit("mm", 0)},
+	0x0208: {Name: "MaxFocalLength", Type: typeShort, Count: 1, Format: formatUnit("mm", 0)},
+	0x020A: {Name: "MaxAperture", Type: typeShort, Count: 1, Format: formatOlympusAperture},
+	0x0303: {Name: "ExtenderModel", Type: typeASCII},
+	0x1002: {Name: "FlashModel", Type: typeASCII},
+}
+
+var olympusCameraSettingsTags = tagTable{
+	0x0200: {Name: "ExposureMode", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		1: "Manual", 2: "Program", 3: "Aperture-priority AE", 4: "Shutter speed priority AE", 5: "Program-shift",
+	})},
+	0x0202: {Name: "MeteringMode", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		2: "Center-weighted average", 3: "Spot", 5: "ESP", 261: "Pattern+AF",
+		515: "Spot+Highlight control", 1027: "Spot+Shadow control",
+	})},
+	0x0301: {Name: "FocusMode", Type: typeShort, Format: formatEnum(map[int]string{
+		0: "Single AF", 1: "Sequential shooting AF", 2: "Continuous AF", 3: "Multi AF",
+		4: "Face detect", 10: "MF",
+	})},
+	0x0501: {Name: "WhiteBalanceTemperature", Type: typeShort, Count: 1, Format: func(value interface{}) string {
+		v, ok := firstInt(value)
+		if !ok || v == 0 {
+			return "Auto"
+		}
+		return fmt.Sprintf("%d K", v)
+	}},
+	0x0507: {Name: "ColorSpace", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		0: "sRGB", 1: "Adobe RGB", 2: "Pro Photo RGB",
+	})},
+	0x0520: {Name: "PictureMode", Type: typeShort, Format: formatEnum(map[int]string{
+		1: "Vivid", 2: "Natural", 3: "Muted", 4: "Portrait", 5: "i-Enhance", 6: "e-Portrait",
+		7: "Color Creator", 8: "Underwater", 9: "Color Profile 1", 10: "Color Profile 2",
+		11: "Color Profile 3", 12: "Monochrome Profile 1", 13: "Monochrome Profile 2",
+		14: "Monochrome Profile 3", 17: "Art Mode", 18: "Monochrome Profile 4",
+		256: "Monotone", 512: "Sepia",
+	})},
+	0x0600: {Name: "DriveMode", Type: typeShort, Format: formatEnum(map[int]string{
+		0: "Single Shot", 1: "Continuous Shooting", 2: "Exposure Bracketing",
+		3: "White Balance Bracketing", 4: "Exposure+WB Bracketing",
+	})},
+	0x0604: {Name: "ImageStabilization", Type: typeLong, Count: 1, Format: formatEnum(map[int]string{
+		0: "Off", 1: "On, Mode 1", 2: "On, Mode 2", 3: "On, Mode 3", 4: "On, Mode 4",
+	})},
+}
+
+// parseOlympusMakerNote decodes an Olympus or OM System MakerNote
+func parseOlympusMakerNote(p *SimpleExifParser, r *tiffReader, offset, size int) {
+	note := r.data[offset : offset+size]
+
+	var header int
+	switch {
+	case strings.HasPrefix(string(note), "OLYMPUS\x00"):
+		header = 8
+	case strings.HasPrefix(string(note), "OM SYSTEM\x00\x00\x00"):
+		header = 12
+	case strings.HasPrefix(string(note), "OLYMP\x00"):
+		parseOlympusIFD(p, r, offset+8)
+		return
+	default:
+		return
+	}
+
+	if len(note) < header+4 {
+		return
+	}
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if string(note[header:header+2]) == "MM" {
+		byteOrder = binary.BigEndian
+	}
+	parseOlympusIFD(p, r.embedded(offset, offset+size, byteOrder), header+4)
+}
+
+// parseOlympusIFD reports the main IFD and follows the Equipment and
+// CameraSettings sub-IFDs; their entries may be stored as IFD pointers or
+// as UNDEFINED blocks, but both hold the sub-IFD offset in the entry
+func parseOlympusIFD(p *SimpleExifParser, r *tiffReader, offset int) {
+	p.parseMakerNoteIFD(r, offset, GroupOlympus, olympusTags, func(tag uint16, raw interface{}, entryOffset int) bool {
+		var tags tagTable
+		switch tag {
+		case olympusEquipment:
+			tags = olympusEquipmentTags
+		case olympusCameraSettings:
+			tags = olympusCameraSettingsTags
+		default:
+			return false
+		}
+		subOffset := int(r.byteOrder.Uint32(r.data[entryOffset+8 : entryOffset+12]))
+		p.parseMakerNoteIFD(r, subOffset, GroupOlympus, tags, nil)
+		return true
+	})
+}
+
+// formatOlympusAperture converts the lens aperture code, where
+// f-number = sqrt(2)^(value/256)
+func formatOlympusAperture(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok || v == 0 {
+		return ""
+	}
+	return "f/" + formatDecimal(math.Pow(math.Sqrt2, float64(v)/256), 1)
+}
+
+// formatOlympusFirmware renders firmware versions, whose hex digits read
+// as the version with a dot before the last three (0x1101 -> "1.101")
+func formatOlympusFirmware(value interface{}) string {
+	v, ok := firstInt(value)
+	if !ok {
+		return ""
+	}
+	text := fmt.Sprintf("%x", v)
+	if len(text) > 3 {
+		text = text[:len(text)-3] + "." + text[len(text)-3:]
+	}
+	return text
+}
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Panasonic MakerNote tags
+// The note starts with "Panasonic\0\0\0" followed by an IFD whose offsets
+// are relative to the TIFF header
+var panasonicTags = tagTable{
+	0x0001: {Name: "ImageQuality", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		1: "TIFF", 2: "High", 3: "Normal", 6: "Very High", 7: "RAW", 9: "Motion Picture",
+		11: "Full HD Movie", 12: "4k Movie",
+	})},
+	0x0002: {Name: "FirmwareVersion", Type: typeUndefined, Count: 4, Format: formatPanasonicFirmware},
+	0x0003: {Name: "WhiteBalance", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		1: "Auto", 2: "Daylight", 3: "Cloudy", 4: "Incandescent", 5: "Manual", 8: "Flash",
+		10: "Black & White", 11: "Manual 2", 12: "Shade", 13: "Kelvin", 14: "Manual 3",
+		15: "Manual 4", 19: "Auto (cool)", 20: "Auto (warm)",
+	})},
+	0x0007: {Name: "FocusMode", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		1: "Auto", 2: "Manual", 4: "Auto, Focus button", 5: "Auto, Continuous",
+		6: "AF-S", 7: "AF-C", 8: "AF-F",
+	})},
+	0x001A: {Name: "ImageStabilization", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		2: "On, Optical", 3: "Off", 4: "On, Mode 2", 5: "On, Optical Panning", 6: "On, Body-only",
+		7: "On, Body-only Panning", 9: "Dual IS", 10: "Dual IS 2", 12: "Dual IS 2 Panning",
+	})},
+	0x001C: {Name: "MacroMode", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		1: "On", 2: "Off", 257: "Tele-Macro", 513: "Macro Zoom",
+	})},
+	0x0025: {Name: "InternalSerialNumber", Type: typeUndefined, Count: 16, Format: formatTagValue},
+	0x0029: {Name: "TimeSincePowerOn", Type: typeLong, Count: 1, Format: func(value interface{}) string {
+		v, ok := firstFloat(value)
+		if !ok {
+			return ""
+		}
+		return formatDecimal(v/100, 2) + " s"
+	}},
+	0x0051: {Name: "LensType", Type: typeASCII},
+	0x0052: {Name: "LensSerialNumber", Type: typeASCII},
+	0x0053: {Name: "AccessoryType", Type: typeASCII},
+	0x0089: {Name: "PhotoStyle", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		0: "Auto", 1: "Standard or Custom", 2: "Vivid", 3: "Natural", 4: "Monochrome",
+		5: "Scenery", 6: "Portrait", 8: "Cinelike D", 9: "Cinelike V", 11: "L. Monochrome",
+		12: "Like709", 15: "L. Monochrome D", 17: "V-Log", 18: "Cinelike D2",
+	})},
+	0x009F: {Name: "ShutterType", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		0: "Mechanical", 1: "Electronic", 2: "Hybrid",
+	})},
+	0x8000: {Name: "MakerNoteVersion", Type: typeUndefined, Count: 4, Format: formatVersion},
+}
+
+// parsePanasonicMakerNote decodes a Panasonic MakerNote
+func parsePanasonicMakerNote(p *SimpleExifParser, r *tiffReader, offset, size int) {
+	note := r.data[offset : offset+size]
+	if !strings.HasPrefix(string(note), "Panasonic\x00\x00\x00") {
+		return
+	}
+	p.parseMakerNoteIFD(r, offset+12, GroupPanasonic, panasonicTags, nil)
+}
+
+// formatPanasonicFirmware renders the 4 version bytes as "0.1.1.2"
+// (some bodies store them as ASCII digits instead)
+func formatPanasonicFirmware(value interface{}) string {
+	raw, ok := value.([]byte)
+	if !ok || len(raw) != 4 {
+		return formatTagValue(value)
+	}
+	if isPrintableText(raw) {
+		return fmt.Sprintf("%c.%c.%c.%c", raw[0], raw[1], raw[2], raw[3])
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", raw[0], raw[1], raw[2], raw[3])
+}
@@ -1,6 +1,9 @@
 package parser
 
-import "io"
+import (
+	"encoding/binary"
+	"io"
+)
 
 // ImageFormat represents supported image formats
 type ImageFormat int
@@ -11,16 +14,18 @@ const (
 	FormatTIFF
 	FormatPNG    // Future support
 	FormatWebP   // Future support
-	FormatHEIF   // Future support
+	FormatHEIF
+	FormatAVIF
 )
 
-// ExifData represents extracted EXIF metadata
+// ExifData is the legacy flat view of extracted metadata
+// Use Metadata.ExifData to produce it from a structured result
 type ExifData map[string]string
 
 // Parser is the interface for image format parsers
 type Parser interface {
-	// Parse extracts EXIF data from the image
-	Parse(data []byte) (ExifData, error)
+	// Parse extracts metadata from the image
+	Parse(data []byte) (*Metadata, error)
 
 	// SupportsFormat checks if this parser supports the given format
 	SupportsFormat(format ImageFormat) bool
@@ -54,14 +59,43 @@ func DetectFormat(data []byte) ImageFormat {
 		return FormatWebP
 	}
 
-	// HEIF: ftyp heic/heix/mif1
+	// HEIF: ftyp heic/heix/mif1, AVIF: ftyp avif/avis
 	if len(data) >= 12 && data[4] == 0x66 && data[5] == 0x74 && data[6] == 0x79 && data[7] == 0x70 {
+		if isAVIFBrand(data) {
+			return FormatAVIF
+		}
 		return FormatHEIF
 	}
 
 	return FormatUnknown
 }
 
+// isAVIFBrand checks the ftyp box for an AVIF brand
+// The major brand is checked first; a generic major brand (e.g. mif1)
+// is resolved from the compatible brands when they are available
+func isAVIFBrand(data []byte) bool {
+	major := string(data[8:12])
+	if major == "avif" || major == "avis" {
+		return true
+	}
+
+	ftypSize := int(binary.BigEndian.Uint32(data[0:4]))
+	if ftypSize > len(data) {
+		ftypSize = len(data)
+	}
+
+	avif := false
+	for i := 16; i+4 <= ftypSize; i += 4 {
+		switch string(data[i : i+4]) {
+		case "avif", "avis":
+			avif = true
+		case "heic", "heix", "heim", "heis", "hevc", "hevx":
+			return false
+		}
+	}
+	return avif
+}
+
 // GetParser returns the appropriate parser for the given format
 func GetParser(format ImageFormat) Parser {
 	switch format {
@@ -73,13 +107,15 @@ func GetParser(format ImageFormat) Parser {
 		return &WebPParser{}
 	case FormatHEIF:
 		return &HEIFParser{}
+	case FormatAVIF:
+		return &AVIFParser{}
 	default:
 		return nil
 	}
 }
 
-// ParseImage is a convenience function that detects format and parses EXIF data
-func ParseImage(data []byte) (ExifData, error) {
+// ParseImage is a convenience function that detects format and parses metadata
+func ParseImage(data []byte) (*Metadata, error) {
 	format := DetectFormat(data)
 	parser := GetParser(format)
 
@@ -87,7 +123,14 @@ func ParseImage(data []byte) (ExifData, error) {
 		return nil, &UnsupportedFormatError{Format: format}
 	}
 
-	return parser.Parse(data)
+	md, err := parser.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Drone attitude combines EXIF, MakerNote and XMP values
+	reportDrone(md)
+	return md, nil
 }
 
 // UnsupportedFormatError is returned when the image format is not supported
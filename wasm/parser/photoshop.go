@@ -0,0 +1,274 @@
+package parser
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// photoshopSignature starts the JPEG APP13 segments holding Photoshop
+// image resource blocks (IRB)
+const photoshopSignature = "Photoshop 3.0\x00"
+
+// Photoshop image resource IDs
+const (
+	psResolutionInfo = 0x03ED
+	psIPTC           = 0x0404
+	psJPEGQuality    = 0x0406
+	psCopyrightFlag  = 0x040A
+	psURL            = 0x040B
+	psThumbnail      = 0x040C
+	psGlobalAngle    = 0x040D
+	psICCProfile     = 0x040F
+	psGlobalAltitude = 0x0419
+	psSlices         = 0x041A
+	psVersionInfo    = 0x0421
+	psEXIF           = 0x0422
+	psXMP            = 0x0424
+	psIPTCDigest     = 0x0425
+	psClippingPath   = 0x0BB7
+)
+
+// photoshopResourceNames names the resources listed in "Resources"
+var photoshopResourceNames = map[int]string{
+	0x03E9: "MacintoshPrintInfo", 0x03ED: "ResolutionInfo", 0x03F3: "PrintFlags",
+	0x03F5: "ColorHalftoneInfo", 0x03F8: "ColorTransferFuncs", 0x0400: "LayerState",
+	0x0402: "LayerGroups", 0x0404: "IPTC-NAA", 0x0406: "JPEG_Quality", 0x0408: "GridGuidesInfo",
+	0x040A: "CopyrightFlag", 0x040B: "URL", 0x040C: "PhotoshopThumbnail", 0x040D: "GlobalAngle",
+	0x040F: "ICC_Profile", 0x0411: "ICC_Untagged", 0x0414: "IDsBaseValue", 0x0419: "GlobalAltitude",
+	0x041A: "Slices", 0x041E: "URL_List", 0x0421: "VersionInfo", 0x0422: "EXIFInfo",
+	0x0424: "XMP", 0x0425: "IPTCDigest", 0x0426: "PrintScale", 0x0428: "PixelInfo",
+	0x0429: "LayerComps", 0x043A: "PrintInfo", 0x043B: "PrintStyle", 0x2710: "PrintFlagsInfo",
+	0x0BB7: "ClippingPathName",
+}
+
+// photoshopResource is one resource of an image resource block
+type photoshopResource struct {
+	ID     int
+	Name   string
+	Data   []byte
+	Offset int
+}
+
+// readPhotoshopResources splits an image resource block into resources
+// Each starts with a signature ("8BIM"; older and third-party writers use
+// a few others), the ID, a Pascal name padded to even length and the size
+// of the data, which is also padded to even length
+func readPhotoshopResources(data []byte) []photoshopResource {
+	var resources []photoshopResource
+	c := &byteCursor{data: data}
+	for c.pos+12 <= len(data) {
+		start := c.pos
+		switch c.fourCC() {
+		case "8BIM", "PHUT", "AgHg", "DCSR", "MeSa":
+		default:
+			return resources
+		}
+		id := int(c.u16())
+		nameLen := int(c.u8())
+		name := ""
+		if c.need(nameLen) {
+			name = string(c.data[c.pos : c.pos+nameLen])
+		}
+		c.skip(nameLen + (nameLen+1)%2)
+		size := int(c.u32())
+		if c.err != nil || !c.need(size) {
+			return resources
+		}
+
+		resources = append(resources, photoshopResource{ID: id, Name: name, Data: data[c.pos : c.pos+size], Offset: start})
+		c.pos += size + size%2
+	}
+	return resources
+}
+
+// parsePhotoshopIRB reports the resources of an image resource block
+// offset is the file offset of data
+func parsePhotoshopIRB(p *SimpleExifParser, md *Metadata, data []byte, offset int, source string) {
+	resources := readPhotoshopResources(data)
+
+	var names []string
+	var iptc []byte
+	var digest *photoshopResource
+	for i := range resources {
+		res := &resources[i]
+		resOffset := offset + res.Offset
+		if name, ok := photoshopResourceNames[res.ID]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, fmt.Sprintf("0x%04X", res.ID))
+		}
+
+		switch res.ID {
+		case psResolutionInfo:
+			reportPhotoshopResolution(md, res.Data, resOffset, source)
+		case psIPTC:
+			iptc = res.Data
+			parseIPTC(md, res.Data, resOffset+photoshopDataOffset(res), source)
+		case psJPEGQuality:
+			reportPhotoshopQuality(md, res.Data, resOffset, source)
+		case psCopyrightFlag:
+			if len(res.Data) > 0 {
+				flag := "False"
+				if res.Data[0] != 0 {
+					flag = "True"
+				}
+				md.Set(GroupPhotoshop, "CopyrightFlag", flag, resOffset, source)
+			}
+		case psURL:
+			md.Set(GroupPhotoshop, "URL", string(res.Data), resOffset, source)
+		case psThumbnail:
+			md.Set(GroupPhotoshop, "PhotoshopThumbnail", fmt.Sprintf("(%d bytes)", len(res.Data)), resOffset, source)
+		case psGlobalAngle, psGlobalAltitude:
+			c := &byteCursor{data: res.Data}
+			v := int32(c.u32())
+			if c.err == nil {
+				name := "GlobalAngle"
+				if res.ID == psGlobalAltitude {
+					name = "GlobalAltitude"
+				}
+				md.SetRaw(GroupPhotoshop, name, v, fmt.Sprintf("%d", v), resOffset, source)
+			}
+		case psICCProfile:
+			parseICCProfile(md, res.Data, resOffset+photoshopDataOffset(res), source)
+		case psSlices:
+			reportPhotoshopSlices(md, res.Data, resOffset, source)
+		case psVersionInfo:
+			reportPhotoshopVersion(md, res.Data, resOffset, source)
+		case psEXIF:
+			if err := p.ParseTIFF(res.Data, resOffset+photoshopDataOffset(res), source, md); err != nil {
+				md.Set(GroupErrors, "EXIF_ParseError", err.Error(), resOffset, source)
+			}
+		case psXMP:
+			reportXMP(md, res.Data, resOffset+photoshopDataOffset(res), source)
+		case psIPTCDigest:
+			digest = res
+		case psClippingPath:
+			if len(res.Data) > 0 && int(res.Data[0]) < len(res.Data) {
+				md.Set(GroupPhotoshop, "ClippingPathName", string(res.Data[1:1+int(res.Data[0])]), resOffset, source)
+			}
+		}
+	}
+
+	md.Set(GroupPhotoshop, "IRB", fmt.Sprintf("%d resources (%d bytes)", len(resources), len(data)), offset, source)
+	if len(names) > 0 {
+		md.Set(GroupPhotoshop, "Resources", strings.Join(names, ", "), offset, source)
+	}
+
+	// The digest is the MD5 of the IPTC block as last saved by Photoshop;
+	// a mismatch means another program edited the IPTC afterwards
+	if digest != nil && len(digest.Data) == 16 {
+		value := hex.EncodeToString(digest.Data)
+		sum := md5.Sum(iptc)
+		switch {
+		case iptc == nil:
+			value += " (no IPTC block)"
+		case bytes.Equal(sum[:], digest.Data):
+			value += " (matches IPTC)"
+		default:
+			value += " (does not match IPTC)"
+		}
+		md.Set(GroupPhotoshop, "IPTCDigest", value, offset+digest.Offset, source)
+	}
+}
+
+// photoshopDataOffset returns the position of a resource's data relative
+// to the start of the resource
+func photoshopDataOffset(res *photoshopResource) int {
+	nameLen := len(res.Name)
+	return 4 + 2 + 1 + nameLen + (nameLen+1)%2 + 4
+}
+
+// reportPhotoshopResolution reports ResolutionInfo: 16.16 fixed-point
+// resolutions with their display units
+func reportPhotoshopResolution(md *Metadata, data []byte, offset int, source string) {
+	c := &byteCursor{data: data}
+	xRes, xUnit := c.u32(), c.u16()
+	c.skip(2)
+	yRes, yUnit := c.u32(), c.u16()
+	if c.err != nil {
+		return
+	}
+
+	units := map[int]string{1: "pixels/inch", 2: "pixels/cm"}
+	md.SetRaw(GroupPhotoshop, "XResolution", float64(xRes)/65536,
+		formatDecimal(float64(xRes)/65536, 2)+" "+lookupCode(units, int(xUnit)), offset, source)
+	md.SetRaw(GroupPhotoshop, "YResolution", float64(yRes)/65536,
+		formatDecimal(float64(yRes)/65536, 2)+" "+lookupCode(units, int(yUnit)), offset, source)
+}
+
+// reportPhotoshopQuality reports the "Save for Web"/"Save As" JPEG quality
+func reportPhotoshopQuality(md *Metadata, data []byte, offset int, source string) {
+	c := &byteCursor{data: data}
+	quality, format, scans := int16(c.u16()), c.u16(), c.u16()
+	if c.err != nil {
+		return
+	}
+	md.SetRaw(GroupPhotoshop, "PhotoshopQuality", int(quality)+4, fmt.Sprintf("%d", int(quality)+4), offset, source)
+	md.Set(GroupPhotoshop, "PhotoshopFormat", lookupCode(map[int]string{
+		0x0000: "Standard", 0x0001: "Optimized", 0x0101: "Progressive",
+	}, int(format)), offset, source)
+	if format == 0x0101 {
+		md.SetRaw(GroupPhotoshop, "ProgressiveScans", int(scans)+2, fmt.Sprintf("%d", int(scans)+2), offset, source)
+	}
+}
+
+// reportPhotoshopSlices reports the slice group of a version 6 Slices
+// resource (later versions store the slices as an action descriptor)
+func reportPhotoshopSlices(md *Metadata, data []byte, offset int, source string) {
+	c := &byteCursor{data: data}
+	version := c.u32()
+	if c.err != nil {
+		return
+	}
+	if version != 6 {
+		md.Set(GroupPhotoshop, "Slices", fmt.Sprintf("version %d", version), offset, source)
+		return
+	}
+
+	top, left, bottom, right := c.u32(), c.u32(), c.u32(), c.u32()
+	name := c.unicodeString()
+	count := c.u32()
+	if c.err != nil {
+		return
+	}
+	md.Set(GroupPhotoshop, "SlicesGroupName", name, offset, source)
+	md.SetRaw(GroupPhotoshop, "NumSlices", count, fmt.Sprintf("%d", count), offset, source)
+	md.Set(GroupPhotoshop, "SlicesBounds", fmt.Sprintf("%dx%d", right-left, bottom-top), offset, source)
+}
+
+// reportPhotoshopVersion reports the application that wrote the file
+func reportPhotoshopVersion(md *Metadata, data []byte, offset int, source string) {
+	c := &byteCursor{data: data}
+	c.skip(4)
+	merged := c.u8()
+	writer := c.unicodeString()
+	reader := c.unicodeString()
+	if c.err != nil {
+		return
+	}
+	hasMerged := "No"
+	if merged != 0 {
+		hasMerged = "Yes"
+	}
+	md.Set(GroupPhotoshop, "HasRealMergedData", hasMerged, offset, source)
+	md.Set(GroupPhotoshop, "WriterName", writer, offset, source)
+	md.Set(GroupPhotoshop, "ReaderName", reader, offset, source)
+}
+
+// unicodeString reads a Photoshop Unicode string: a 4-byte character
+// count followed by UTF-16BE code units
+func (c *byteCursor) unicodeString() string {
+	n := int(c.u32())
+	if n < 0 || !c.need(2*n) {
+		return ""
+	}
+	units := make([]uint16, n)
+	for i := range units {
+		units[i] = c.u16()
+	}
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+}
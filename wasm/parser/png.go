@@ -21,13 +21,13 @@ type PNGParser struct {
 var pngSignature = []byte{137, 80, 78, 71, 13, 10, 26, 10}
 
 // Parse extracts EXIF/metadata from PNG images
-func (p *PNGParser) Parse(data []byte) (ExifData, error) {
+func (p *PNGParser) Parse(data []byte) (*Metadata, error) {
 	// Initialize embedded parser
 	if p.exifParser == nil {
 		p.exifParser = &SimpleExifParser{}
 	}
 
-	exifData := make(ExifData)
+	md := NewMetadata(FormatPNG)
 
 	// Verify PNG signature
 	if len(data) < 8 {
@@ -41,6 +41,9 @@ func (p *PNGParser) Parse(data []byte) (ExifData, error) {
 	// Parse chunks starting at offset 8
 	offset := 8
 
+	// end is the offset just past the IEND chunk
+	end := -1
+
 	for offset < len(data) {
 		// Read chunk header (8 bytes: 4-byte length + 4-byte type)
 		if offset+8 > len(data) {
@@ -65,72 +68,81 @@ func (p *PNGParser) Parse(data []byte) (ExifData, error) {
 		crcData := append([]byte(chunkType), chunkData...)
 		calculatedCRC := crc32.ChecksumIEEE(crcData)
 		if calculatedCRC != chunkCRC {
-			exifData["PNG_Warning"] = fmt.Sprintf("CRC mismatch for chunk %s", chunkType)
+			md.Set(GroupPNG, "Warning", fmt.Sprintf("CRC mismatch for chunk %s", chunkType), offset, chunkType)
 		}
 
 		// Process metadata chunks
 		switch chunkType {
 		case "IHDR":
 			// Image header - width, height, bit depth, color type
-			p.parseIHDR(chunkData, exifData)
+			p.parseIHDR(chunkData, offset, md)
 
 		case "tEXt":
 			// Latin-1 text chunk
-			p.parseTEXt(chunkData, exifData)
+			p.parseTEXt(chunkData, offset, md)
 
 		case "zTXt":
 			// Compressed text chunk
-			p.parseZTXt(chunkData, exifData)
+			p.parseZTXt(chunkData, offset, md)
 
 		case "iTXt":
 			// International text chunk (UTF-8)
-			p.parseITXt(chunkData, exifData)
+			p.parseITXt(chunkData, offset, md)
 
 		case "eXIf":
 			// EXIF metadata (TIFF format)
-			if err := p.exifParser.ParseTIFF(chunkData, exifData); err != nil {
-				exifData["EXIF_ParseError"] = err.Error()
+			if err := p.exifParser.ParseTIFF(chunkData, offset, chunkType, md); err != nil {
+				md.Set(GroupErrors, "EXIF_ParseError", err.Error(), offset, chunkType)
 			}
 
 		case "pHYs":
 			// Physical pixel dimensions
-			p.parsePHYs(chunkData, exifData)
+			p.parsePHYs(chunkData, offset, md)
 
 		case "tIME":
 			// Last modification time
-			p.parseTIME(chunkData, exifData)
+			p.parseTIME(chunkData, offset, md)
 
 		case "iCCP":
 			// ICC color profile
-			p.parseICCP(chunkData, exifData)
+			p.parseICCP(chunkData, offset, md)
 
 		case "sPLT":
 			// Suggested palette
 			if len(chunkData) > 0 {
 				nullPos := bytes.IndexByte(chunkData, 0)
 				if nullPos > 0 {
-					exifData["PNG_Palette"] = string(chunkData[:nullPos])
+					md.Set(GroupPNG, "Palette", string(chunkData[:nullPos]), offset, chunkType)
 				}
 			}
 
+		case "caBX":
+			// C2PA manifest store (JUMBF)
+			reportC2PA(md, data, chunkData, offset, chunkType)
+
 		case "IEND":
-			// End of PNG data stream
-			break
+			// End of PNG data stream; anything after it is a trailer
+			end = offset + int(chunkLength) + 4
 		}
 
 		// Move to next chunk
 		offset += int(chunkLength) + 4 // data + CRC
+		if end >= 0 {
+			break
+		}
 	}
 
-	if len(exifData) == 0 {
+	reportTrailer(md, data, end, "IEND")
+
+	if md.Len() == 0 {
 		return nil, fmt.Errorf("no metadata found in PNG")
 	}
 
-	return exifData, nil
+	return md, nil
 }
 
 // parseIHDR extracts image header information
-func (p *PNGParser) parseIHDR(data []byte, exifData ExifData) {
+func (p *PNGParser) parseIHDR(data []byte, offset int, md *Metadata) {
 	if len(data) < 13 {
 		return
 	}
@@ -143,9 +155,9 @@ func (p *PNGParser) parseIHDR(data []byte, exifData ExifData) {
 	filterMethod := data[11]
 	interlaceMethod := data[12]
 
-	exifData["PNG_ImageWidth"] = strconv.FormatUint(uint64(width), 10)
-	exifData["PNG_ImageHeight"] = strconv.FormatUint(uint64(height), 10)
-	exifData["PNG_BitDepth"] = strconv.Itoa(int(bitDepth))
+	md.SetRaw(GroupPNG, "ImageWidth", width, strconv.FormatUint(uint64(width), 10), offset, "IHDR")
+	md.SetRaw(GroupPNG, "ImageHeight", height, strconv.FormatUint(uint64(height), 10), offset, "IHDR")
+	md.SetRaw(GroupPNG, "BitDepth", bitDepth, strconv.Itoa(int(bitDepth)), offset, "IHDR")
 
 	// Color type descriptions
 	colorTypes := map[byte]string{
@@ -156,23 +168,23 @@ func (p *PNGParser) parseIHDR(data []byte, exifData ExifData) {
 		6: "RGB with Alpha",
 	}
 	if desc, ok := colorTypes[colorType]; ok {
-		exifData["PNG_ColorType"] = desc
+		md.SetRaw(GroupPNG, "ColorType", colorType, desc, offset, "IHDR")
 	} else {
-		exifData["PNG_ColorType"] = strconv.Itoa(int(colorType))
+		md.Set(GroupPNG, "ColorType", strconv.Itoa(int(colorType)), offset, "IHDR")
 	}
 
-	exifData["PNG_Compression"] = strconv.Itoa(int(compressionMethod))
-	exifData["PNG_Filter"] = strconv.Itoa(int(filterMethod))
+	md.Set(GroupPNG, "Compression", strconv.Itoa(int(compressionMethod)), offset, "IHDR")
+	md.Set(GroupPNG, "Filter", strconv.Itoa(int(filterMethod)), offset, "IHDR")
 
 	if interlaceMethod == 0 {
-		exifData["PNG_Interlace"] = "None"
+		md.Set(GroupPNG, "Interlace", "None", offset, "IHDR")
 	} else if interlaceMethod == 1 {
-		exifData["PNG_Interlace"] = "Adam7"
+		md.Set(GroupPNG, "Interlace", "Adam7", offset, "IHDR")
 	}
 }
 
 // parseTEXt extracts Latin-1 text metadata
-func (p *PNGParser) parseTEXt(data []byte, exifData ExifData) {
+func (p *PNGParser) parseTEXt(data []byte, offset int, md *Metadata) {
 	// Find null separator
 	nullPos := bytes.IndexByte(data, 0)
 	if nullPos < 0 || nullPos >= len(data)-1 {
@@ -182,11 +194,11 @@ func (p *PNGParser) parseTEXt(data []byte, exifData ExifData) {
 	keyword := string(data[:nullPos])
 	text := string(data[nullPos+1:])
 
-	exifData["PNG_"+keyword] = text
+	md.Set(GroupPNG, keyword, text, offset, "tEXt")
 }
 
 // parseZTXt extracts compressed text metadata
-func (p *PNGParser) parseZTXt(data []byte, exifData ExifData) {
+func (p *PNGParser) parseZTXt(data []byte, offset int, md *Metadata) {
 	// Find null separator
 	nullPos := bytes.IndexByte(data, 0)
 	if nullPos < 0 || nullPos+2 >= len(data) {
@@ -216,11 +228,11 @@ func (p *PNGParser) parseZTXt(data []byte, exifData ExifData) {
 
 	text := buf.String()
 
-	exifData["PNG_"+keyword] = text
+	md.Set(GroupPNG, keyword, text, offset, "zTXt")
 }
 
 // parseITXt extracts international text metadata (UTF-8)
-func (p *PNGParser) parseITXt(data []byte, exifData ExifData) {
+func (p *PNGParser) parseITXt(data []byte, offset int, md *Metadata) {
 	// Find first null separator (after keyword)
 	nullPos1 := bytes.IndexByte(data, 0)
 	if nullPos1 < 0 || nullPos1+2 >= len(data) {
@@ -270,8 +282,13 @@ func (p *PNGParser) parseITXt(data []byte, exifData ExifData) {
 		text = string(textData)
 	}
 
+	if keyword == "XML:com.adobe.xmp" {
+		reportXMP(md, []byte(text), offset, "iTXt")
+		return
+	}
+
 	// Build key with language and translated keyword if available
-	key := "PNG_" + keyword
+	key := keyword
 	if languageTag != "" || translatedKeyword != "" {
 		details := []string{}
 		if languageTag != "" {
@@ -283,11 +300,11 @@ func (p *PNGParser) parseITXt(data []byte, exifData ExifData) {
 		key += " (" + strings.Join(details, ", ") + ")"
 	}
 
-	exifData[key] = text
+	md.Set(GroupPNG, key, text, offset, "iTXt")
 }
 
 // parsePHYs extracts physical pixel dimensions
-func (p *PNGParser) parsePHYs(data []byte, exifData ExifData) {
+func (p *PNGParser) parsePHYs(data []byte, offset int, md *Metadata) {
 	if len(data) < 9 {
 		return
 	}
@@ -303,13 +320,13 @@ func (p *PNGParser) parsePHYs(data []byte, exifData ExifData) {
 		unitStr = "meter"
 	}
 
-	exifData["PNG_PixelsPerUnitX"] = strconv.FormatUint(uint64(pixelsPerUnitX), 10)
-	exifData["PNG_PixelsPerUnitY"] = strconv.FormatUint(uint64(pixelsPerUnitY), 10)
-	exifData["PNG_PixelUnit"] = unitStr
+	md.SetRaw(GroupPNG, "PixelsPerUnitX", pixelsPerUnitX, strconv.FormatUint(uint64(pixelsPerUnitX), 10), offset, "pHYs")
+	md.SetRaw(GroupPNG, "PixelsPerUnitY", pixelsPerUnitY, strconv.FormatUint(uint64(pixelsPerUnitY), 10), offset, "pHYs")
+	md.Set(GroupPNG, "PixelUnit", unitStr, offset, "pHYs")
 }
 
 // parseTIME extracts last modification time
-func (p *PNGParser) parseTIME(data []byte, exifData ExifData) {
+func (p *PNGParser) parseTIME(data []byte, offset int, md *Metadata) {
 	if len(data) < 7 {
 		return
 	}
@@ -324,11 +341,11 @@ func (p *PNGParser) parseTIME(data []byte, exifData ExifData) {
 	timeStr := fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d",
 		year, month, day, hour, minute, second)
 
-	exifData["PNG_ModifyDate"] = timeStr
+	md.Set(GroupPNG, "ModifyDate", timeStr, offset, "tIME")
 }
 
-// parseICCP extracts ICC color profile name
-func (p *PNGParser) parseICCP(data []byte, exifData ExifData) {
+// parseICCP extracts the ICC color profile name and decodes the profile
+func (p *PNGParser) parseICCP(data []byte, offset int, md *Metadata) {
 	// Find null separator
 	nullPos := bytes.IndexByte(data, 0)
 	if nullPos < 0 || nullPos+2 >= len(data) {
@@ -338,13 +355,17 @@ func (p *PNGParser) parseICCP(data []byte, exifData ExifData) {
 	profileName := string(data[:nullPos])
 	compressionMethod := data[nullPos+1]
 
-	exifData["PNG_ICCProfile"] = profileName
+	md.Set(GroupPNG, "ICCProfile", profileName, offset, "iCCP")
 
 	// Only deflate (method 0) is supported for ICC profile
 	if compressionMethod == 0 {
-		// We could decompress and parse the ICC profile here
-		// For now, just note that it exists
-		exifData["PNG_ICCCompression"] = "deflate"
+		md.Set(GroupPNG, "ICCCompression", "deflate", offset, "iCCP")
+		profile, err := inflateICCProfile(data[nullPos+2:])
+		if err != nil {
+			md.Set(GroupErrors, "ICC_ParseError", err.Error(), offset, "iCCP")
+			return
+		}
+		parseICCProfile(md, profile, offset, "iCCP")
 	}
 }
 
@@ -9,53 +9,102 @@ import (
 
 // SimpleExifParser is a basic EXIF parser without external dependencies
 // Uses only Go standard library for TinyGo compatibility
-type SimpleExifParser struct{}
+type SimpleExifParser struct {
+	// nested is set while parsing an image embedded in another JPEG
+	// (an MPF secondary image), whose MP Index is not followed again
+	nested bool
+}
 
 // EXIF tag IDs
 const (
 	// IFD0 tags (basic image info)
-	tagImageDescription  = 0x010E
-	tagMake              = 0x010F
-	tagModel             = 0x0110
-	tagOrientation       = 0x0112
-	tagXResolution       = 0x011A
-	tagYResolution       = 0x011B
-	tagResolutionUnit    = 0x0128
-	tagSoftware          = 0x0131
-	tagDateTime          = 0x0132
-	tagArtist            = 0x013B
-	tagCopyright         = 0x8298
-	tagExifIFDPointer    = 0x8769
-	tagGPSInfoIFDPointer = 0x8825
+	tagImageWidth                = 0x0100
+	tagImageLength               = 0x0101
+	tagBitsPerSample             = 0x0102
+	tagCompression               = 0x0103
+	tagPhotometricInterpretation = 0x0106
+	tagStripOffsets              = 0x0111
+	tagSamplesPerPixel           = 0x0115
+	tagPlanarConfiguration       = 0x011C
+	tagImageDescription          = 0x010E
+	tagMake                      = 0x010F
+	tagModel                     = 0x0110
+	tagOrientation               = 0x0112
+	tagXResolution               = 0x011A
+	tagYResolution               = 0x011B
+	tagResolutionUnit            = 0x0128
+	tagSoftware                  = 0x0131
+	tagDateTime                  = 0x0132
+	tagArtist                    = 0x013B
+	tagApplicationNotes          = 0x02BC
+	tagIPTCNAA                   = 0x83BB
+	tagImageResources            = 0x8649
+	tagInterColorProfile         = 0x8773
+	tagCopyright                 = 0x8298
+	tagExifIFDPointer            = 0x8769
+	tagGPSInfoIFDPointer         = 0x8825
+	tagInteropIFDPointer         = 0xA005
 
 	// EXIF Sub-IFD tags
-	tagExposureTime       = 0x829A
-	tagFNumber            = 0x829D
-	tagISOSpeedRatings    = 0x8827
-	tagDateTimeOriginal   = 0x9003
-	tagDateTimeDigitized  = 0x9004
-	tagFocalLength        = 0x920A
-	tagFlash              = 0x9209
-	tagUserComment        = 0x9286
-	tagImageUniqueID      = 0xA420
-	tagWhiteBalance       = 0xA403
-	tagCameraOwnerName    = 0xA430
-	tagBodySerialNumber   = 0xA431
-	tagLensMake           = 0xA433
-	tagLensModel          = 0xA434
-	tagLensSerialNumber   = 0xA435
+	tagExposureTime      = 0x829A
+	tagFNumber           = 0x829D
+	tagISOSpeedRatings   = 0x8827
+	tagDateTimeOriginal  = 0x9003
+	tagDateTimeDigitized = 0x9004
+	tagFocalLength       = 0x920A
+	tagFlash             = 0x9209
+	tagMakerNote         = 0x927C
+	tagUserComment       = 0x9286
+	tagImageUniqueID     = 0xA420
+	tagWhiteBalance      = 0xA403
+	tagCameraOwnerName   = 0xA430
+	tagBodySerialNumber  = 0xA431
+	tagLensMake          = 0xA433
+	tagLensModel         = 0xA434
+	tagLensSerialNumber  = 0xA435
+	tagLensSpecification = 0xA432
+	tagExposureBiasValue = 0x9204
+	tagSubjectArea       = 0x9214
 
 	// GPS tags
-	tagGPSLatitudeRef  = 0x0001
-	tagGPSLatitude     = 0x0002
-	tagGPSLongitudeRef = 0x0003
-	tagGPSLongitude    = 0x0004
+	tagGPSVersionID         = 0x0000
+	tagGPSLatitudeRef       = 0x0001
+	tagGPSLatitude          = 0x0002
+	tagGPSLongitudeRef      = 0x0003
+	tagGPSLongitude         = 0x0004
+	tagGPSAltitudeRef       = 0x0005
+	tagGPSAltitude          = 0x0006
+	tagGPSTimeStamp         = 0x0007
+	tagGPSSatellites        = 0x0008
+	tagGPSStatus            = 0x0009
+	tagGPSMeasureMode       = 0x000A
+	tagGPSDOP               = 0x000B
+	tagGPSSpeedRef          = 0x000C
+	tagGPSSpeed             = 0x000D
+	tagGPSTrackRef          = 0x000E
+	tagGPSTrack             = 0x000F
+	tagGPSImgDirectionRef   = 0x0010
+	tagGPSImgDirection      = 0x0011
+	tagGPSMapDatum          = 0x0012
+	tagGPSDestLatitudeRef   = 0x0013
+	tagGPSDestLatitude      = 0x0014
+	tagGPSDestLongitudeRef  = 0x0015
+	tagGPSDestLongitude     = 0x0016
+	tagGPSDestBearingRef    = 0x0017
+	tagGPSDestBearing       = 0x0018
+	tagGPSDestDistanceRef   = 0x0019
+	tagGPSDestDistance      = 0x001A
+	tagGPSProcessingMethod  = 0x001B
+	tagGPSAreaInformation   = 0x001C
+	tagGPSDateStamp         = 0x001D
+	tagGPSDifferential      = 0x001E
+	tagGPSHPositioningError = 0x001F
 )
 
 // Parse implements the Parser interface for SimpleExifParser
-func (p *SimpleExifParser) Parse(data []byte) (ExifData, error) {
+func (p *SimpleExifParser) Parse(data []byte) (*Metadata, error) {
 	// Extract all JPEG metadata (EXIF, JFIF, XMP, ICC Profile, Comments, etc.)
-	exifData := make(ExifData)
+	md := NewMetadata(FormatJPEG)
 
 	reader := bytes.NewReader(data)
 
@@ -69,8 +118,33 @@ func (p *SimpleExifParser) Parse(data []byte) (ExifData, error) {
 		return nil, fmt.Errorf("not a valid JPEG file (header: %02X %02X)", header[0], header[1])
 	}
 
+	// Extended XMP segments are merged once the main packet has been read
+	var xmpExtensions []xmpExtension
+
+	// Photoshop resources may continue across APP13 segments, so the
+	// block is decoded once all of them have been read. Offsets are those
+	// of the first segment's block
+	var irb []byte
+	irbOffset := -1
+
+	// ICC profiles larger than a segment are split into numbered chunks
+	var iccChunks []iccChunk
+
+	// DHT and DQT segments are summarised once the first scan is reached
+	var tables jpegTables
+
+	// The MP Index is read after the frame header so the primary image's
+	// size is known
+	var mpf []byte
+	mpfOffset := -1
+
+	// JUMBF boxes (C2PA manifests) are split across APP11 segments
+	var jumbf jumbfSegments
+
 	// Parse all segments
 	for {
+		markerOffset := len(data) - reader.Len()
+
 		var marker [2]byte
 		if _, err := reader.Read(marker[:]); err != nil {
 			if err == io.EOF {
@@ -99,89 +173,174 @@ func (p *SimpleExifParser) Parse(data []byte) (ExifData, error) {
 		}
 
 		// Read segment data
+		segmentOffset := markerOffset + 4
 		segmentData := make([]byte, size-2)
 		if _, err := reader.Read(segmentData); err != nil {
 			break
 		}
 
+		source := segmentName(marker[1])
+
+		if isSOFMarker(marker[1]) {
+			reportJPEGFrame(md, marker[1], segmentData, segmentOffset, source)
+		}
+
 		// Process different segment types
 		switch marker[1] {
+		case 0xC4: // DHT - Huffman tables
+			tables.countHuffmanTables(segmentData, segmentOffset)
+
+		case 0xDB: // DQT - Quantization tables
+			tables.countQuantizationTables(segmentData, segmentOffset)
+
+		case 0xDD: // DRI - Restart interval
+			reportRestartInterval(md, segmentData, segmentOffset, source)
+
 		case 0xFE: // COM - Comment
-			exifData["JPEG_Comment"] = string(segmentData)
+			md.Set(GroupJPEG, "Comment", string(segmentData), segmentOffset, source)
 
 		case 0xE0: // APP0 - JFIF/JFXX
-			if len(segmentData) >= 5 && string(segmentData[0:5]) == "JFIF\x00" {
-				exifData["JFIF_Version"] = fmt.Sprintf("%d.%02d", segmentData[5], segmentData[6])
+			if len(segmentData) >= 7 && string(segmentData[0:5]) == "JFIF\x00" {
+				md.Set(GroupJFIF, "Version", fmt.Sprintf("%d.%02d", segmentData[5], segmentData[6]), segmentOffset, source)
 			} else if len(segmentData) >= 5 && string(segmentData[0:5]) == "JFXX\x00" {
-				exifData["JFXX_Extension"] = "present"
+				md.Set(GroupJFIF, "JFXX_Extension", "present", segmentOffset, source)
 			} else if len(segmentData) > 0 {
 				// Other APP0 data
-				exifData["APP0_Data"] = fmt.Sprintf("(%d bytes)", len(segmentData))
+				md.Set(GroupJPEG, "APP0_Data", fmt.Sprintf("(%d bytes)", len(segmentData)), segmentOffset, source)
 			}
 
 		case 0xE1: // APP1 - EXIF or XMP
 			if len(segmentData) >= 6 && string(segmentData[0:4]) == "Exif" {
 				// Parse EXIF
-				if err := p.ParseTIFF(segmentData[6:], exifData); err != nil {
-					exifData["EXIF_ParseError"] = err.Error()
+				if err := p.ParseTIFF(segmentData[6:], segmentOffset+6, source, md); err != nil {
+					md.Set(GroupErrors, "EXIF_ParseError", err.Error(), segmentOffset, source)
 				}
 			} else if len(segmentData) >= 29 && string(segmentData[0:29]) == "http://ns.adobe.com/xap/1.0/\x00" {
 				// XMP metadata
-				xmpData := string(segmentData[29:])
-				exifData["XMP_Metadata"] = xmpData
+				reportXMP(md, segmentData[29:], segmentOffset+29, source)
+			} else if ext, ok := parseXMPExtension(segmentData, segmentOffset); ok {
+				xmpExtensions = append(xmpExtensions, ext)
 			} else if len(segmentData) > 0 {
-				exifData["APP1_Data"] = fmt.Sprintf("(%d bytes)", len(segmentData))
+				md.Set(GroupJPEG, "APP1_Data", fmt.Sprintf("(%d bytes)", len(segmentData)), segmentOffset, source)
 			}
 
 		case 0xE2: // APP2 - ICC Profile or FlashPix
-			if len(segmentData) >= 14 && string(segmentData[0:12]) == "ICC_PROFILE\x00" {
-				exifData["ICC_Profile"] = fmt.Sprintf("present (%d bytes)", len(segmentData))
+			if len(segmentData) >= 14 && string(segmentData[0:12]) == iccSignature {
+				iccChunks = append(iccChunks, iccChunk{
+					sequence: int(segmentData[12]),
+					count:    int(segmentData[13]),
+					data:     segmentData[14:],
+					offset:   segmentOffset + 14,
+				})
+			} else if len(segmentData) >= 12 && string(segmentData[0:4]) == mpfSignature && mpfOffset < 0 {
+				mpf = segmentData[4:]
+				mpfOffset = segmentOffset + 4
+			} else if bytes.HasPrefix(segmentData, []byte(isoGainMapSignature)) {
+				reportISOGainMap(md, segmentData[len(isoGainMapSignature):], segmentOffset+len(isoGainMapSignature), source)
 			} else if len(segmentData) >= 6 && string(segmentData[0:6]) == "FPXR\x00\x00" {
-				exifData["FlashPix"] = "present"
+				md.Set(GroupJPEG, "FlashPix", "present", segmentOffset, source)
 			} else if len(segmentData) > 0 {
-				exifData["APP2_Data"] = fmt.Sprintf("(%d bytes)", len(segmentData))
+				md.Set(GroupJPEG, "APP2_Data", fmt.Sprintf("(%d bytes)", len(segmentData)), segmentOffset, source)
+			}
+
+		case 0xEB: // APP11 - JUMBF
+			if !jumbf.add(segmentData, segmentOffset) && len(segmentData) > 0 {
+				md.Set(GroupJPEG, "APP11_Data", fmt.Sprintf("(%d bytes binary)", len(segmentData)), segmentOffset, source)
 			}
 
-		case 0xE3, 0xE4, 0xE5, 0xE6, 0xE7, 0xE8, 0xE9, 0xEA, 0xEB, 0xEC: // APP3-APP12
+		case 0xE3, 0xE4, 0xE5, 0xE6, 0xE7, 0xE8, 0xE9, 0xEA, 0xEC: // APP3-APP10, APP12
 			// Generic APP marker
-			appNum := marker[1] - 0xE0
-			key := fmt.Sprintf("APP%d_Data", appNum)
+			key := source + "_Data"
 			// Try to detect text content
 			if p.isPrintable(segmentData) && len(segmentData) > 0 {
-				exifData[key] = string(segmentData)
+				md.Set(GroupJPEG, key, string(segmentData), segmentOffset, source)
 			} else {
-				exifData[key] = fmt.Sprintf("(%d bytes binary)", len(segmentData))
+				md.Set(GroupJPEG, key, fmt.Sprintf("(%d bytes binary)", len(segmentData)), segmentOffset, source)
 			}
 
 		case 0xED: // APP13 - Photoshop IRB
-			if len(segmentData) >= 14 && string(segmentData[0:14]) == "Photoshop 3.0\x00" {
-				exifData["Photoshop_IRB"] = fmt.Sprintf("present (%d bytes)", len(segmentData))
+			if len(segmentData) >= 14 && string(segmentData[0:14]) == photoshopSignature {
+				if irbOffset < 0 {
+					irbOffset = segmentOffset + 14
+				}
+				irb = append(irb, segmentData[14:]...)
 			} else if len(segmentData) > 0 {
-				exifData["APP13_Data"] = fmt.Sprintf("(%d bytes)", len(segmentData))
+				md.Set(GroupJPEG, "APP13_Data", fmt.Sprintf("(%d bytes)", len(segmentData)), segmentOffset, source)
 			}
 
 		case 0xEE: // APP14 - Adobe
 			if len(segmentData) >= 5 && string(segmentData[0:5]) == "Adobe" {
-				exifData["Adobe_APP14"] = "present"
+				md.Set(GroupAdobe, "APP14", "present", segmentOffset, source)
 			} else if len(segmentData) > 0 {
-				exifData["APP14_Data"] = fmt.Sprintf("(%d bytes)", len(segmentData))
+				md.Set(GroupJPEG, "APP14_Data", fmt.Sprintf("(%d bytes)", len(segmentData)), segmentOffset, source)
 			}
 
 		case 0xEF: // APP15
 			if p.isPrintable(segmentData) && len(segmentData) > 0 {
-				exifData["APP15_Data"] = string(segmentData)
+				md.Set(GroupJPEG, "APP15_Data", string(segmentData), segmentOffset, source)
 			} else {
-				exifData["APP15_Data"] = fmt.Sprintf("(%d bytes binary)", len(segmentData))
+				md.Set(GroupJPEG, "APP15_Data", fmt.Sprintf("(%d bytes binary)", len(segmentData)), segmentOffset, source)
 			}
 		}
 	}
 
+	tables.report(md)
+	checkJPEGDimensions(md)
+	if len(iccChunks) > 0 {
+		reportJPEGICCProfile(md, iccChunks, "APP2")
+	}
+	if irbOffset >= 0 {
+		parsePhotoshopIRB(p, md, irb, irbOffset, "APP13")
+	}
+	if len(xmpExtensions) > 0 {
+		reportExtendedXMP(md, xmpExtensions, "APP1")
+	}
+	if mpfOffset >= 0 {
+		p.parseMPF(md, data, mpf, mpfOffset, "APP2")
+	}
+	if len(jumbf.order) > 0 {
+		// Hash binding exclusions are offsets in the file, which do not
+		// apply to MPF images parsed on their own
+		file := data
+		if p.nested {
+			file = nil
+		}
+		for _, instance := range jumbf.order {
+			reportC2PA(md, file, jumbf.boxes[instance], jumbf.offset[instance], "APP11")
+		}
+	}
+	if !p.nested {
+		end := jpegImageEnd(data)
+		reportGainMap(md, data, end)
+		reportContainerMedia(md, data, end)
+		reportTrailer(md, data, end, "EOI")
+	}
+
 	// Return data even if no EXIF found
-	if len(exifData) == 0 {
+	if md.Len() == 0 {
 		return nil, fmt.Errorf("no metadata found in JPEG")
 	}
 
-	return exifData, nil
+	return md, nil
+}
+
+// segmentName returns the conventional name of a JPEG marker (e.g. "APP1")
+func segmentName(marker byte) string {
+	switch {
+	case marker >= 0xE0 && marker <= 0xEF:
+		return fmt.Sprintf("APP%d", marker-0xE0)
+	case marker == 0xFE:
+		return "COM"
+	case marker == 0xC4:
+		return "DHT"
+	case marker == 0xDB:
+		return "DQT"
+	case marker == 0xDD:
+		return "DRI"
+	case isSOFMarker(marker):
+		return fmt.Sprintf("SOF%d", marker-0xC0)
+	}
+	return fmt.Sprintf("0xFF%02X", marker)
 }
 
 // isPrintable checks if data is mostly printable text
@@ -198,9 +357,34 @@ func (p *SimpleExifParser) isPrintable(data []byte) bool {
 	return float64(printable)/float64(len(data)) > 0.8
 }
 
-// ParseTIFF parses TIFF-formatted EXIF data
+// tiffReader carries the state shared while walking the IFDs of one TIFF structure
+type tiffReader struct {
+	data      []byte
+	byteOrder binary.ByteOrder
+
+	// base is the absolute file offset of data[0]
+	base int
+
+	// source names the segment or chunk the TIFF structure was found in
+	source string
+
+	md *Metadata
+}
+
+// ifdGroups maps each IFD to the metadata group it is reported in
+var ifdGroups = map[ifdKind]string{
+	ifdIFD0:    GroupIFD0,
+	ifdExif:    GroupExif,
+	ifdGPS:     GroupGPS,
+	ifdInterop: GroupInterop,
+	ifdIFD1:    GroupIFD1,
+}
+
+// ParseTIFF parses TIFF-formatted EXIF data into md
+// base is the absolute file offset of data (used for entry offsets) and
+// source names the segment or chunk it came from
 // This is exported so other parsers (like WebPParser) can reuse it
-func (p *SimpleExifParser) ParseTIFF(data []byte, exifData ExifData) error {
+func (p *SimpleExifParser) ParseTIFF(data []byte, base int, source string, md *Metadata) error {
 	if len(data) < 8 {
 		return fmt.Errorf("TIFF header too short")
 	}
@@ -215,17 +399,20 @@ func (p *SimpleExifParser) ParseTIFF(data []byte, exifData ExifData) error {
 		return fmt.Errorf("invalid TIFF byte order")
 	}
 
+	r := &tiffReader{data: data, byteOrder: byteOrder, base: base, source: source, md: md}
+
 	// Get IFD offset
 	ifdOffset := byteOrder.Uint32(data[4:8])
 
 	// Parse IFD
-	p.parseIFD(data, int(ifdOffset), byteOrder, exifData)
+	p.parseIFD(r, int(ifdOffset), ifdIFD0)
 
 	return nil
 }
 
-func (p *SimpleExifParser) parseIFD(data []byte, offset int, byteOrder binary.ByteOrder, exifData ExifData) {
-	if offset+2 > len(data) {
+func (p *SimpleExifParser) parseIFD(r *tiffReader, offset int, kind ifdKind) {
+	data, byteOrder := r.data, r.byteOrder
+	if offset < 0 || offset+2 > len(data) {
 		return
 	}
 
@@ -241,140 +428,138 @@ func (p *SimpleExifParser) parseIFD(data []byte, offset int, byteOrder binary.By
 		tag := byteOrder.Uint16(data[entryOffset : entryOffset+2])
 		dataType := byteOrder.Uint16(data[entryOffset+2 : entryOffset+4])
 		count := byteOrder.Uint32(data[entryOffset+4 : entryOffset+8])
-		valueOffset := entryOffset + 8
 
-		p.parseTag(tag, dataType, count, valueOffset, data, byteOrder, exifData)
+		p.parseTag(r, tag, dataType, count, entryOffset, kind)
 	}
-}
 
-func (p *SimpleExifParser) parseTag(tag uint16, dataType uint16, count uint32, offset int, data []byte, byteOrder binary.ByteOrder, exifData ExifData) {
-	var value string
+	// IFD0 is followed by IFD1 (the thumbnail IFD) when the next-IFD offset is set
+	if kind != ifdIFD0 {
+		return
+	}
+	nextOffset := offset + int(numEntries)*12
+	if nextOffset+4 > len(data) {
+		return
+	}
+	next := int(byteOrder.Uint32(data[nextOffset : nextOffset+4]))
+	if next != 0 && next != offset-2 {
+		p.parseIFD(r, next, ifdIFD1)
+		p.reportThumbnail(r)
+	}
+}
 
-	switch dataType {
-	case 2: // ASCII string
-		if count <= 4 {
-			value = string(bytes.TrimRight(data[offset:offset+int(count)], "\x00"))
-		} else {
-			valueOffset := int(byteOrder.Uint32(data[offset : offset+4]))
-			if valueOffset+int(count) <= len(data) {
-				value = string(bytes.TrimRight(data[valueOffset:valueOffset+int(count)], "\x00"))
-			}
+// reportThumbnail summarises the thumbnail declared by IFD1
+func (p *SimpleExifParser) reportThumbnail(r *tiffReader) {
+	if !isThumbnailIFD(r.md) {
+		return
+	}
+	compression := r.md.Get(GroupIFD1, "Compression")
+	start := r.md.Get(GroupIFD1, "JPEGInterchangeFormat")
+	length := r.md.Get(GroupIFD1, "JPEGInterchangeFormatLength")
+
+	if start != nil && length != nil {
+		offset, _ := firstInt(start.Raw)
+		size, _ := firstInt(length.Raw)
+		value := fmt.Sprintf("JPEG, %d bytes", size)
+		if offset+size > len(r.data) {
+			value += " (truncated)"
 		}
-
-	case 3: // SHORT (2 bytes)
-		if count == 1 {
-			value = fmt.Sprintf("%d", byteOrder.Uint16(data[offset:offset+2]))
+		r.md.Set(GroupIFD1, "ThumbnailImage", value, r.base+offset, r.source)
+		if r.md.thumbnail == nil && r.md.strips == nil && offset >= 0 && size > 0 && offset+size <= len(r.data) {
+			r.md.thumbnail = &Thumbnail{MIMEType: "image/jpeg", Data: r.data[offset : offset+size]}
 		}
-
-	case 4: // LONG (4 bytes)
-		if count == 1 {
-			value = fmt.Sprintf("%d", byteOrder.Uint32(data[offset:offset+4]))
+	} else if compression != nil && r.md.Get(GroupIFD1, "StripOffsets") != nil {
+		r.md.Set(GroupIFD1, "ThumbnailImage", "Strips ("+compression.Value+")", -1, r.source)
+		if r.md.thumbnail == nil && r.md.strips == nil {
+			r.md.strips = locateStripThumbnail(r)
 		}
+	}
+}
 
-	case 5: // RATIONAL (8 bytes)
-		if count == 1 {
-			valueOffset := int(byteOrder.Uint32(data[offset : offset+4]))
-			if valueOffset+8 <= len(data) {
-				num := byteOrder.Uint32(data[valueOffset : valueOffset+4])
-				den := byteOrder.Uint32(data[valueOffset+4 : valueOffset+8])
-				if den != 0 {
-					value = fmt.Sprintf("%.2f", float64(num)/float64(den))
-				}
-			}
-		}
+// parseTag decodes one IFD entry starting at entryOffset
+func (p *SimpleExifParser) parseTag(r *tiffReader, tag uint16, dataType uint16, count uint32, entryOffset int, kind ifdKind) {
+	data, byteOrder := r.data, r.byteOrder
+	offset := entryOffset + 8
 
-	case 7: // UNDEFINED (used by UserComment)
-		// Special handling for UserComment tag (0x9286)
-		if tag == tagUserComment && count > 8 {
+	// Sub-IFD pointers are followed rather than reported
+	if kind == ifdIFD0 {
+		switch tag {
+		case tagExifIFDPointer:
 			valueOffset := int(byteOrder.Uint32(data[offset : offset+4]))
-			if valueOffset+int(count) <= len(data) {
-				commentData := data[valueOffset : valueOffset+int(count)]
-				// First 8 bytes indicate character code
-				// "ASCII\x00\x00\x00" = ASCII
-				// "JIS\x00\x00\x00\x00\x00" = JIS
-				// "UNICODE\x00" = Unicode
-				// "\x00\x00\x00\x00\x00\x00\x00\x00" = Undefined
-				if len(commentData) > 8 {
-					charset := string(bytes.TrimRight(commentData[0:8], "\x00"))
-					commentText := commentData[8:]
-
-					// Remove null bytes and convert to string
-					value = string(bytes.TrimRight(commentText, "\x00"))
-
-					// Add charset info if not ASCII or undefined
-					if charset != "" && charset != "ASCII" {
-						value = fmt.Sprintf("%s (charset: %s)", value, charset)
-					}
-				}
-			}
+			p.parseIFD(r, valueOffset, ifdExif)
+			return
+		case tagGPSInfoIFDPointer:
+			valueOffset := int(byteOrder.Uint32(data[offset : offset+4]))
+			p.parseGPSIFD(r, valueOffset)
+			return
 		}
 	}
-
-	if value == "" {
+	if kind == ifdExif && tag == tagInteropIFDPointer {
+		valueOffset := int(byteOrder.Uint32(data[offset : offset+4]))
+		p.parseIFD(r, valueOffset, ifdInterop)
 		return
 	}
 
-	// Map tag to name
-	var tagName string
-	switch tag {
-	// IFD0 tags
-	case tagImageDescription:
-		tagName = "ImageDescription"
-	case tagMake:
-		tagName = "Make"
-	case tagModel:
-		tagName = "Model"
-	case tagOrientation:
-		tagName = "Orientation"
-	case tagSoftware:
-		tagName = "Software"
-	case tagDateTime:
-		tagName = "DateTime"
-	case tagArtist:
-		tagName = "Artist"
-	case tagCopyright:
-		tagName = "Copyright"
-	// EXIF Sub-IFD tags
-	case tagExposureTime:
-		tagName = "ExposureTime"
-	case tagFNumber:
-		tagName = "FNumber"
-	case tagISOSpeedRatings:
-		tagName = "ISO"
-	case tagDateTimeOriginal:
-		tagName = "DateTimeOriginal"
-	case tagDateTimeDigitized:
-		tagName = "DateTimeDigitized"
-	case tagFocalLength:
-		tagName = "FocalLength"
-	case tagFlash:
-		tagName = "Flash"
-	case tagUserComment:
-		tagName = "UserComment"
-	case tagImageUniqueID:
-		tagName = "ImageUniqueID"
-	case tagWhiteBalance:
-		tagName = "WhiteBalance"
-	case tagCameraOwnerName:
-		tagName = "CameraOwnerName"
-	case tagBodySerialNumber:
-		tagName = "BodySerialNumber"
-	case tagLensMake:
-		tagName = "LensMake"
-	case tagLensModel:
-		tagName = "LensModel"
-	case tagLensSerialNumber:
-		tagName = "LensSerialNumber"
-	case tagExifIFDPointer:
-		// Parse EXIF sub-IFD
-		valueOffset := int(byteOrder.Uint32(data[offset : offset+4]))
-		p.parseIFD(data, valueOffset, byteOrder, exifData)
+	decoded, ok := readTagValue(dataType, count, offset, data, byteOrder)
+	if !ok {
 		return
-	default:
+	}
+
+	info := lookupTag(kind, tag)
+	r.checkFormat(ifdGroups[kind], info, dataType, count, entryOffset)
+
+	value := formatTagValue(decoded)
+	if info.Format != nil {
+		value = info.Format(decoded)
+	}
+
+	if value == "" {
 		return
 	}
 
-	exifData[tagName] = value
+	r.set(ifdGroups[kind], tag, info.Name, decoded, value, entryOffset)
+
+	if kind == ifdIFD0 {
+		// Embedded XMP, IPTC, Photoshop and ICC blocks of TIFF files
+		if raw, ok := valueBytes(dataType, count, offset, data, byteOrder); ok && len(raw) > 4 {
+			rawOffset := r.base + int(byteOrder.Uint32(data[offset:offset+4]))
+			switch tag {
+			case tagApplicationNotes:
+				reportXMP(r.md, raw, rawOffset, r.source)
+			case tagIPTCNAA:
+				parseIPTC(r.md, raw, rawOffset, r.source)
+			case tagImageResources:
+				parsePhotoshopIRB(p, r.md, raw, rawOffset, r.source)
+			case tagInterColorProfile:
+				parseICCProfile(r.md, raw, rawOffset, r.source)
+			}
+		}
+	}
+	if kind == ifdExif && tag == tagMakerNote {
+		if raw, ok := decoded.([]byte); ok && len(raw) > 4 {
+			p.parseMakerNote(r, int(byteOrder.Uint32(data[offset:offset+4])), len(raw))
+		}
+	}
+}
+
+// set stores a decoded tag value in the given group
+func (r *tiffReader) set(group string, tag uint16, name string, raw interface{}, value string, entryOffset int) {
+	tagID := tag
+	r.md.Group(group).Set(&Entry{
+		TagID:  &tagID,
+		Name:   name,
+		Raw:    raw,
+		Value:  value,
+		Offset: r.base + entryOffset,
+		Source: r.source,
+	})
+}
+
+// checkFormat reports an entry whose type or count differs from its tag
+// description; the value itself is still decoded from the stored type
+func (r *tiffReader) checkFormat(group string, info tagInfo, dataType uint16, count uint32, entryOffset int) {
+	if mismatch := info.formatMismatch(dataType, count); mismatch != "" {
+		r.md.Set(GroupErrors, group+"_"+info.Name+"_Format", mismatch, r.base+entryOffset, r.source)
+	}
 }
 
 // SupportsFormat returns true for JPEG format
@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Sony MakerNote tags
+// Older bodies prefix the IFD with "SONY DSC \0\0\0" or "SONY CAM \0\0\0";
+// Alpha bodies store a plain IFD. Offsets are relative to the TIFF header.
+const (
+	sonyTag2010 = 0x2010
+	sonyTag9050 = 0x9050
+)
+
+var sonyTags = tagTable{
+	0x0102: {Name: "Quality", Type: typeLong, Count: 1, Format: formatEnum(map[int]string{
+		0: "RAW", 1: "Super Fine", 2: "Fine", 3: "Standard", 4: "Economy", 5: "Extra Fine",
+		6: "RAW + JPEG/HEIF", 7: "Compressed RAW", 8: "Compressed RAW + JPEG", 9: "Light",
+	})},
+	0x0104: {Name: "FlashExposureComp", Type: typeSRational, Count: 1, Format: formatEV},
+	0x0115: {Name: "WhiteBalance", Type: typeLong, Count: 1, Format: formatEnum(map[int]string{
+		0: "Auto", 4: "Custom", 5: "Daylight", 6: "Cloudy", 7: "Cool White Fluorescent",
+		8: "Day White Fluorescent", 9: "Daylight Fluorescent", 10: "Incandescent2",
+		11: "Warm White Fluorescent", 14: "Incandescent", 15: "Flash",
+		17: "Underwater 1 (Blue Water)", 18: "Underwater 2 (Green Water)", 19: "Underwater Auto",
+	})},
+	0x2009: {Name: "HighISONoiseReduction", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		0: "Off", 1: "Low", 2: "Normal", 3: "High", 256: "Auto", 65535: "n/a",
+	})},
+	0x201B: {Name: "FocusMode", Type: typeByte, Count: 1, Format: formatEnum(map[int]string{
+		0: "Manual", 2: "AF-S", 3: "AF-C", 4: "AF-A", 6: "DMF", 7: "AF-D",
+	})},
+	0x2031: {Name: "SerialNumber", Type: typeASCII},
+	0xB001: {Name: "SonyModelID", Type: typeShort, Count: 1},
+	0xB020: {Name: "CreativeStyle", Type: typeASCII},
+	0xB021: {Name: "ColorTemperature", Type: typeLong, Count: 1, Format: formatUnit("K", 0)},
+	0xB025: {Name: "DynamicRangeOptimizer", Type: typeLong, Count: 1, Format: formatEnum(map[int]string{
+		0: "Off", 1: "Standard", 2: "Advanced Auto", 3: "Auto",
+		8: "Advanced Lv1", 9: "Advanced Lv2", 10: "Advanced Lv3", 11: "Advanced Lv4", 12: "Advanced Lv5",
+		16: "Lv1", 17: "Lv2", 18: "Lv3", 19: "Lv4", 20: "Lv5",
+	})},
+	0xB026: {Name: "ImageStabilization", Type: typeLong, Count: 1, Format: formatEnum(map[int]string{
+		0: "Off", 1: "On",
+	})},
+	0xB027: {Name: "LensType", Type: typeLong, Count: 1},
+	0xB041: {Name: "ExposureMode", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		0: "Program AE", 1: "Portrait", 2: "Beach", 3: "Sports", 4: "Snow", 5: "Landscape",
+		6: "Auto", 7: "Aperture-priority AE", 8: "Shutter speed priority AE",
+		9: "Night Scene / Twilight", 10: "Hi-Speed Shutter", 11: "Twilight Portrait",
+		12: "Soft Snap/Portrait", 13: "Fireworks", 14: "Smile Shutter", 15: "Manual",
+		18: "High Sensitivity", 19: "Macro", 20: "Advanced Sports Shooting", 29: "Underwater",
+		33: "Food", 34: "Sweep Panorama", 35: "Handheld Night Shot", 36: "Anti Motion Blur",
+		37: "Pet", 38: "Backlight Correction HDR", 39: "Superior Auto", 40: "Background Defocus",
+		41: "Soft Skin", 42: "3D Image", 65535: "n/a",
+	})},
+	0xB042: {Name: "FocusMode2", Type: typeShort, Count: 1, Format: formatEnum(map[int]string{
+		1: "AF-S", 2: "AF-C", 4: "Permanent-AF", 65535: "n/a",
+	})},
+}
+
+// sonyDecipherTable inverts Sony's byte cipher, which replaces each byte
+// b < 249 with b*b*b % 249 (bytes 249-255 are stored unchanged)
+var sonyDecipherTable = func() [256]byte {
+	var table [256]byte
+	for b := 0; b < 256; b++ {
+		table[b] = byte(b)
+	}
+	for b := 0; b < 249; b++ {
+		table[b*b*b%249] = byte(b)
+	}
+	return table
+}()
+
+// sonyDecipher returns a deciphered copy of an enciphered block
+func sonyDecipher(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i, b := range data {
+		out[i] = sonyDecipherTable[b]
+	}
+	return out
+}
+
+// parseSonyMakerNote decodes a Sony MakerNote
+func parseSonyMakerNote(p *SimpleExifParser, r *tiffReader, offset, size int) {
+	note := r.data[offset : offset+size]
+	if len(note) >= 12 && (strings.HasPrefix(string(note), "SONY DSC ") || strings.HasPrefix(string(note), "SONY CAM ")) {
+		offset += 12
+	}
+
+	model := makerNoteModel(r)
+	p.parseMakerNoteIFD(r, offset, GroupSony, sonyTags, func(tag uint16, raw interface{}, entryOffset int) bool {
+		data, ok := raw.([]byte)
+		if !ok {
+			return false
+		}
+		switch tag {
+		case sonyTag9050:
+			reportSonyTag9050(r, model, sonyDecipher(data), entryOffset)
+		case sonyTag2010:
+			reportSonyTag2010(r, sonyDecipher(data), entryOffset)
+		default:
+			return false
+		}
+		return true
+	})
+}
+
+// reportSonyTag9050 reports the shutter count from the enciphered 0x9050
+// block. Its position depends on the generation of the body: the
+// ILCE-7RM2 and later bodies (plus ILCA-99M2 and ZV) moved it from 0x32
+// to 0x3A and use only its low 24 bits
+func reportSonyTag9050(r *tiffReader, model string, data []byte, entryOffset int) {
+	if strings.HasPrefix(model, "DSC-") || strings.HasPrefix(model, "Stellar") {
+		return
+	}
+
+	position, mask := 0x32, uint32(0xFFFFFFFF)
+	newer := []string{
+		"ILCE-1", "ILCE-6100", "ILCE-6300", "ILCE-6400", "ILCE-6500", "ILCE-6600", "ILCE-6700",
+		"ILCE-7C", "ILCE-7M3", "ILCE-7M4", "ILCE-7RM2", "ILCE-7RM3", "ILCE-7RM4", "ILCE-7RM5",
+		"ILCE-7SM2", "ILCE-7SM3", "ILCE-9", "ILCA-99M2", "ZV-",
+	}
+	for _, prefix := range newer {
+		if strings.HasPrefix(model, prefix) {
+			position, mask = 0x3A, 0x00FFFFFF
+			break
+		}
+	}
+	if position+4 > len(data) {
+		return
+	}
+
+	count := r.byteOrder.Uint32(data[position:position+4]) & mask
+	if count == 0 {
+		return
+	}
+	r.set(GroupSony, sonyTag9050, "ShutterCount", []uint32{count}, fmt.Sprintf("%d", count), entryOffset)
+}
+
+// reportSonyTag2010 reports the burst counters at the start of the
+// enciphered 0x2010 block; they are stored zero-based and are common to
+// all of its layouts (the remaining fields move between bodies)
+func reportSonyTag2010(r *tiffReader, data []byte, entryOffset int) {
+	if len(data) < 8 {
+		return
+	}
+	image := r.byteOrder.Uint32(data[0:4]) + 1
+	file := r.byteOrder.Uint32(data[4:8]) + 1
+	r.set(GroupSony, sonyTag2010, "SequenceImageNumber", []uint32{image}, fmt.Sprintf("%d", image), entryOffset)
+	r.set(GroupSony, sonyTag2010, "SequenceFileNumber", []uint32{file}, fmt.Sprintf("%d", file), entryOffset)
+}
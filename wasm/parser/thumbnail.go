@@ -0,0 +1,197 @@
+package parser
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Thumbnail is the preview image embedded in IFD1
+type Thumbnail struct {
+	// MIMEType is "image/jpeg" for JPEG thumbnails and "image/tiff" for
+	// uncompressed strip thumbnails
+	MIMEType string
+
+	// Data is a complete image file that can be decoded on its own
+	Data []byte
+}
+
+// ErrNoThumbnail is returned when the image has no usable IFD1 thumbnail
+var ErrNoThumbnail = errors.New("no embedded thumbnail found")
+
+// maxStripThumbnailSize bounds the pixel data of a strip thumbnail
+const maxStripThumbnailSize = 1 << 20
+
+// maxThumbnailDimension is the largest IFD1 image taken for a thumbnail
+// when NewSubfileType does not mark it as reduced resolution
+const maxThumbnailDimension = 1024
+
+// stripThumbnail locates an uncompressed IFD1 thumbnail; the TIFF file is
+// only built when the thumbnail is requested
+type stripThumbnail struct {
+	data            []byte
+	offsets, counts []int
+	size            int
+
+	width, height, samples, photometric uint32
+	bits                                []uint32
+}
+
+// Thumbnail returns the embedded thumbnail found while parsing, or nil
+// Strip thumbnails are rebuilt as a TIFF file on the first call
+func (m *Metadata) Thumbnail() *Thumbnail {
+	if m.thumbnail == nil && m.strips != nil {
+		m.thumbnail = m.strips.build()
+		m.strips = nil
+	}
+	return m.thumbnail
+}
+
+// ExtractThumbnail parses the image and returns its embedded EXIF thumbnail
+func ExtractThumbnail(data []byte) (*Thumbnail, error) {
+	md, err := ParseImage(data)
+	if err != nil {
+		return nil, err
+	}
+	thumbnail := md.Thumbnail()
+	if thumbnail == nil {
+		return nil, ErrNoThumbnail
+	}
+	return thumbnail, nil
+}
+
+// isThumbnailIFD reports whether IFD1 holds a thumbnail rather than, in a
+// multi-page TIFF, the next page: NewSubfileType must mark it as reduced
+// resolution, or without that tag the image must be small
+func isThumbnailIFD(md *Metadata) bool {
+	if e := md.Get(GroupIFD1, "NewSubfileType"); e != nil {
+		v, _ := firstInt(e.Raw)
+		return v&1 != 0
+	}
+	for _, name := range []string{"ImageWidth", "ImageLength"} {
+		if e := md.Get(GroupIFD1, name); e != nil {
+			if v, _ := firstInt(e.Raw); v > maxThumbnailDimension {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// locateStripThumbnail checks an uncompressed, chunky IFD1 strip
+// thumbnail and records where its strips are
+func locateStripThumbnail(r *tiffReader) *stripThumbnail {
+	ints := func(name string) []float64 {
+		if e := r.md.Get(GroupIFD1, name); e != nil {
+			return valueFloats(e.Raw)
+		}
+		return nil
+	}
+
+	compression := ints("Compression")
+	width, height := ints("ImageWidth"), ints("ImageLength")
+	offsets, counts := ints("StripOffsets"), ints("StripByteCounts")
+	if len(compression) == 0 || compression[0] != 1 || len(width) == 0 || len(height) == 0 ||
+		len(offsets) == 0 || len(offsets) != len(counts) {
+		return nil
+	}
+	// Planar data would need its strips interleaved
+	if v := ints("PlanarConfiguration"); len(v) > 0 && v[0] == 2 {
+		return nil
+	}
+
+	t := &stripThumbnail{data: r.data, width: uint32(width[0]), height: uint32(height[0])}
+	for i := range offsets {
+		start, size := int(offsets[i]), int(counts[i])
+		if start < 0 || size < 0 || start > len(r.data) || size > len(r.data)-start ||
+			size > maxStripThumbnailSize-t.size {
+			return nil
+		}
+		t.offsets = append(t.offsets, start)
+		t.counts = append(t.counts, size)
+		t.size += size
+	}
+
+	t.samples = 3
+	if v := ints("SamplesPerPixel"); len(v) > 0 {
+		t.samples = uint32(v[0])
+	}
+	if t.samples == 0 || t.samples > 16 {
+		return nil
+	}
+	perSample := ints("BitsPerSample")
+	t.bits = make([]uint32, t.samples)
+	for i := range t.bits {
+		t.bits[i] = 8
+		if i < len(perSample) {
+			t.bits[i] = uint32(perSample[i])
+		}
+	}
+	t.photometric = 2
+	if v := ints("PhotometricInterpretation"); len(v) > 0 {
+		t.photometric = uint32(v[0])
+	}
+	return t
+}
+
+// build rebuilds the thumbnail as a standalone little-endian TIFF with a
+// single strip
+func (t *stripThumbnail) build() *Thumbnail {
+	pixels := make([]byte, 0, t.size)
+	for i, start := range t.offsets {
+		pixels = append(pixels, t.data[start:start+t.counts[i]]...)
+	}
+
+	type field struct {
+		tag, dataType uint16
+		values        []uint32
+	}
+	fields := []field{
+		{tagImageWidth, typeLong, []uint32{t.width}},
+		{tagImageLength, typeLong, []uint32{t.height}},
+		{tagBitsPerSample, typeShort, t.bits},
+		{tagCompression, typeShort, []uint32{1}},
+		{tagPhotometricInterpretation, typeShort, []uint32{t.photometric}},
+		{tagStripOffsets, typeLong, nil},
+		{tagSamplesPerPixel, typeShort, []uint32{t.samples}},
+		{0x0116, typeLong, []uint32{t.height}},            // RowsPerStrip
+		{0x0117, typeLong, []uint32{uint32(len(pixels))}}, // StripByteCounts
+	}
+
+	// Layout: header, IFD, out-of-line values, pixel data
+	le := binary.LittleEndian
+	ifdSize := 2 + len(fields)*12 + 4
+	extra := 0
+	if len(t.bits) > 2 {
+		extra = len(t.bits) * 2
+	}
+	pixelOffset := 8 + ifdSize + extra
+	fields[5].values = []uint32{uint32(pixelOffset)}
+
+	out := make([]byte, pixelOffset, pixelOffset+len(pixels))
+	copy(out, "II")
+	le.PutUint16(out[2:], 42)
+	le.PutUint32(out[4:], 8)
+	le.PutUint16(out[8:], uint16(len(fields)))
+	extraOffset := 8 + ifdSize
+	for i, f := range fields {
+		entry := out[10+i*12:]
+		le.PutUint16(entry[0:], f.tag)
+		le.PutUint16(entry[2:], f.dataType)
+		le.PutUint32(entry[4:], uint32(len(f.values)))
+		value := entry[8:12]
+		if f.dataType == typeShort && len(f.values) > 2 {
+			le.PutUint32(value, uint32(extraOffset))
+			value = out[extraOffset:]
+		}
+		for j, v := range f.values {
+			if f.dataType == typeShort {
+				le.PutUint16(value[j*2:], uint16(v))
+			} else {
+				le.PutUint32(value[j*4:], v)
+			}
+		}
+	}
+	out = append(out, pixels...)
+
+	return &Thumbnail{MIMEType: "image/tiff", Data: out}
+}
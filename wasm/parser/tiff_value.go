@@ -0,0 +1,346 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// TIFF field types
+const (
+	typeByte      = 1
+	typeASCII     = 2
+	typeShort     = 3
+	typeLong      = 4
+	typeRational  = 5
+	typeSByte     = 6
+	typeUndefined = 7
+	typeSShort    = 8
+	typeSLong     = 9
+	typeSRational = 10
+	typeFloat     = 11
+	typeDouble    = 12
+	typeIFD       = 13
+	typeUTF8      = 129 // EXIF 3.0
+)
+
+// maxDisplayValues limits how many array elements are rendered as text
+const maxDisplayValues = 16
+
+// Rational is an unsigned TIFF RATIONAL value
+type Rational struct {
+	Num uint32 `json:"num"`
+	Den uint32 `json:"den"`
+}
+
+// Float returns the rational as a float64 (0 when the denominator is 0)
+func (r Rational) Float() float64 {
+	if r.Den == 0 {
+		return 0
+	}
+	return float64(r.Num) / float64(r.Den)
+}
+
+// SRational is a signed TIFF SRATIONAL value
+type SRational struct {
+	Num int32 `json:"num"`
+	Den int32 `json:"den"`
+}
+
+// Float returns the rational as a float64 (0 when the denominator is 0)
+func (r SRational) Float() float64 {
+	if r.Den == 0 {
+		return 0
+	}
+	return float64(r.Num) / float64(r.Den)
+}
+
+// typeSize returns the size in bytes of one element of a TIFF field type
+func typeSize(dataType uint16) int {
+	switch dataType {
+	case typeByte, typeASCII, typeSByte, typeUndefined, typeUTF8:
+		return 1
+	case typeShort, typeSShort:
+		return 2
+	case typeLong, typeSLong, typeFloat, typeIFD:
+		return 4
+	case typeRational, typeSRational, typeDouble:
+		return 8
+	}
+	return 0
+}
+
+// valueBytes returns the raw bytes of an IFD entry value
+// Values of 4 bytes or less are stored inline in the entry,
+// larger values are stored at the offset held by the entry
+func valueBytes(dataType uint16, count uint32, offset int, data []byte, byteOrder binary.ByteOrder) ([]byte, bool) {
+	size := typeSize(dataType)
+	if size == 0 || count == 0 || offset < 0 || offset+4 > len(data) {
+		return nil, false
+	}
+
+	total := uint64(size) * uint64(count)
+	if total > uint64(len(data)) {
+		return nil, false
+	}
+	if total <= 4 {
+		return data[offset : offset+int(total)], true
+	}
+
+	valueOffset := int(byteOrder.Uint32(data[offset : offset+4]))
+	if valueOffset < 0 || valueOffset+int(total) > len(data) {
+		return nil, false
+	}
+	return data[valueOffset : valueOffset+int(total)], true
+}
+
+// readTagValue decodes an IFD entry value into a typed Go value:
+//
+//	BYTE      -> []uint8      ASCII     -> string (also UTF-8)
+//	SHORT     -> []uint16     LONG, IFD -> []uint32
+//	RATIONAL  -> []Rational   SBYTE     -> []int8
+//	UNDEFINED -> []byte       SSHORT    -> []int16
+//	SLONG     -> []int32      SRATIONAL -> []SRational
+//	FLOAT     -> []float32    DOUBLE    -> []float64
+func readTagValue(dataType uint16, count uint32, offset int, data []byte, byteOrder binary.ByteOrder) (interface{}, bool) {
+	raw, ok := valueBytes(dataType, count, offset, data, byteOrder)
+	if !ok {
+		return nil, false
+	}
+	n := int(count)
+
+	switch dataType {
+	case typeByte:
+		return append([]uint8(nil), raw...), true
+
+	case typeASCII, typeUTF8:
+		// Strings are NUL-terminated; multiple strings may be packed together
+		return strings.TrimRight(string(bytes.TrimRight(raw, "\x00")), " "), true
+
+	case typeUndefined:
+		return append([]byte(nil), raw...), true
+
+	case typeSByte:
+		values := make([]int8, n)
+		for i := range values {
+			values[i] = int8(raw[i])
+		}
+		return values, true
+
+	case typeShort:
+		values := make([]uint16, n)
+		for i := range values {
+			values[i] = byteOrder.Uint16(raw[i*2:])
+		}
+		return values, true
+
+	case typeSShort:
+		values := make([]int16, n)
+		for i := range values {
+			values[i] = int16(byteOrder.Uint16(raw[i*2:]))
+		}
+		return values, true
+
+	case typeLong, typeIFD:
+		values := make([]uint32, n)
+		for i := range values {
+			values[i] = byteOrder.Uint32(raw[i*4:])
+		}
+		return values, true
+
+	case typeSLong:
+		values := make([]int32, n)
+		for i := range values {
+			values[i] = int32(byteOrder.Uint32(raw[i*4:]))
+		}
+		return values, true
+
+	case typeRational:
+		values := make([]Rational, n)
+		for i := range values {
+			values[i] = Rational{byteOrder.Uint32(raw[i*8:]), byteOrder.Uint32(raw[i*8+4:])}
+		}
+		return values, true
+
+	case typeSRational:
+		values := make([]SRational, n)
+		for i := range values {
+			values[i] = SRational{int32(byteOrder.Uint32(raw[i*8:])), int32(byteOrder.Uint32(raw[i*8+4:]))}
+		}
+		return values, true
+
+	case typeFloat:
+		values := make([]float32, n)
+		for i := range values {
+			values[i] = math.Float32frombits(byteOrder.Uint32(raw[i*4:]))
+		}
+		return values, true
+
+	case typeDouble:
+		values := make([]float64, n)
+		for i := range values {
+			values[i] = math.Float64frombits(byteOrder.Uint64(raw[i*8:]))
+		}
+		return values, true
+	}
+
+	return nil, false
+}
+
+// formatTagValue renders a decoded value as display text
+// Arrays are joined with ", " and truncated after maxDisplayValues elements
+func formatTagValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		if isPrintableText(v) {
+			return string(bytes.TrimRight(v, "\x00 "))
+		}
+		return joinValues(len(v), func(i int) string { return fmt.Sprintf("%d", v[i]) })
+	case []int8:
+		return joinValues(len(v), func(i int) string { return fmt.Sprintf("%d", v[i]) })
+	case []uint16:
+		return joinValues(len(v), func(i int) string { return fmt.Sprintf("%d", v[i]) })
+	case []int16:
+		return joinValues(len(v), func(i int) string { return fmt.Sprintf("%d", v[i]) })
+	case []uint32:
+		return joinValues(len(v), func(i int) string { return fmt.Sprintf("%d", v[i]) })
+	case []int32:
+		return joinValues(len(v), func(i int) string { return fmt.Sprintf("%d", v[i]) })
+	case []Rational:
+		return joinValues(len(v), func(i int) string { return formatDecimal(v[i].Float(), 6) })
+	case []SRational:
+		return joinValues(len(v), func(i int) string { return formatDecimal(v[i].Float(), 6) })
+	case []float32:
+		return joinValues(len(v), func(i int) string { return fmt.Sprintf("%g", v[i]) })
+	case []float64:
+		return joinValues(len(v), func(i int) string { return fmt.Sprintf("%g", v[i]) })
+	}
+	return ""
+}
+
+// joinValues joins n formatted elements, truncating long arrays
+func joinValues(n int, format func(i int) string) string {
+	limit := n
+	if limit > maxDisplayValues {
+		limit = maxDisplayValues
+	}
+
+	parts := make([]string, limit)
+	for i := range parts {
+		parts[i] = format(i)
+	}
+
+	text := strings.Join(parts, ", ")
+	if n > limit {
+		text += fmt.Sprintf(", ... (%d values)", n)
+	}
+	return text
+}
+
+// valueLen returns the number of elements in a decoded value
+func valueLen(value interface{}) int {
+	switch v := value.(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case []int8:
+		return len(v)
+	case []uint16:
+		return len(v)
+	case []int16:
+		return len(v)
+	case []uint32:
+		return len(v)
+	case []int32:
+		return len(v)
+	case []Rational:
+		return len(v)
+	case []SRational:
+		return len(v)
+	case []float32:
+		return len(v)
+	case []float64:
+		return len(v)
+	}
+	return 0
+}
+
+// valueFloats converts a numeric value to a slice of float64
+func valueFloats(value interface{}) []float64 {
+	var out []float64
+	switch v := value.(type) {
+	case []uint8:
+		for _, x := range v {
+			out = append(out, float64(x))
+		}
+	case []int8:
+		for _, x := range v {
+			out = append(out, float64(x))
+		}
+	case []uint16:
+		for _, x := range v {
+			out = append(out, float64(x))
+		}
+	case []int16:
+		for _, x := range v {
+			out = append(out, float64(x))
+		}
+	case []uint32:
+		for _, x := range v {
+			out = append(out, float64(x))
+		}
+	case []int32:
+		for _, x := range v {
+			out = append(out, float64(x))
+		}
+	case []Rational:
+		for _, x := range v {
+			out = append(out, x.Float())
+		}
+	case []SRational:
+		for _, x := range v {
+			out = append(out, x.Float())
+		}
+	case []float32:
+		for _, x := range v {
+			out = append(out, float64(x))
+		}
+	case []float64:
+		out = append(out, v...)
+	}
+	return out
+}
+
+// valueString returns the text of an ASCII or UNDEFINED value
+func valueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		if i := bytes.IndexByte(v, 0); i >= 0 {
+			v = v[:i]
+		}
+		return strings.TrimSpace(string(v))
+	}
+	return ""
+}
+
+// isPrintableText reports whether data is entirely printable ASCII
+// (ignoring trailing NUL padding)
+func isPrintableText(data []byte) bool {
+	data = bytes.TrimRight(data, "\x00")
+	if len(data) == 0 {
+		return false
+	}
+	for _, b := range data {
+		if (b < 32 || b > 126) && b != 9 && b != 10 && b != 13 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Data appended after the end of the image stream (JPEG EOI, PNG IEND,
+// the RIFF size of WebP) is invisible to viewers. Camera makers store
+// videos and Samsung SEFT blocks there, but it is also a common way to
+// hide archives or other files inside an image.
+
+// maxTrailerText bounds the text shown for a plain-text trailer
+const maxTrailerText = 64
+
+// maxTrailerItems bounds the number of items classified in a trailer
+const maxTrailerItems = 32
+
+// samsungTrailerNames names the SEFT block types whose name is not stored
+var samsungTrailerNames = map[int]string{
+	0x0001: "SoundShot_Meta_Info",
+	0x0a01: "Image_UTC_Data",
+	0x0a20: "Dual_Shot_Extra_Info",
+	0x0a30: "MotionPhoto_Data",
+	0x0b40: "Camera_Capture_Mode_Info",
+}
+
+// reportTrailer reports the data after the end of the image at end
+// source names the marker or chunk that ended the image ("EOI", "IEND")
+func reportTrailer(md *Metadata, file []byte, end int, source string) {
+	if end <= 0 || end >= len(file) {
+		return
+	}
+	md.Set(GroupTrailer, "TrailerSize", fmt.Sprintf("%d bytes after %s", len(file)-end, source), end, source)
+
+	// A Samsung trailer is found from the end of the file; the rest is
+	// classified from the front
+	limit := len(file)
+	samsung, samsungStart := samsungTrailer(file, end)
+	if samsung != "" {
+		limit = samsungStart
+	}
+
+	n := 0
+	for pos := end; pos < limit; {
+		if n == maxTrailerItems {
+			n++
+			md.Set(GroupTrailer, fmt.Sprintf("Trailer%d", n), fmt.Sprintf("... %d more bytes not classified", limit-pos), pos, source)
+			break
+		}
+		kind, size := classifyTrailer(file[pos:limit])
+		if size <= 0 {
+			size = limit - pos
+		}
+		n++
+		value := fmt.Sprintf("%s, %d bytes", kind, size)
+		for _, media := range md.media {
+			if media.Offset == pos {
+				value += " (" + media.Semantic + ")"
+			}
+		}
+		md.Set(GroupTrailer, fmt.Sprintf("Trailer%d", n), value, pos, source)
+		pos += size
+	}
+	if samsung != "" {
+		n++
+		md.Set(GroupTrailer, fmt.Sprintf("Trailer%d", n), samsung, samsungStart, source)
+	}
+}
+
+// classifyTrailer names the data at the start of a trailer and returns
+// the size of the item when its format gives one (0 = up to the end)
+func classifyTrailer(data []byte) (string, int) {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return "ZIP archive", 0
+	case bytes.HasPrefix(data, []byte("Rar!\x1a\x07")):
+		return "RAR archive", 0
+	case bytes.HasPrefix(data, []byte("7z\xbc\xaf\x27\x1c")):
+		return "7-Zip archive", 0
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return "PDF document", 0
+	case len(data) >= 3 && data[0] == 0xFF && data[1] == 0xD8 && data[2] == 0xFF:
+		if end := jpegImageEnd(data); end > 0 {
+			return "JPEG image", end
+		}
+		return "JPEG image (truncated)", 0
+	case bytes.HasPrefix(data, pngSignature):
+		return "PNG image", 0
+	case isMP4(data):
+		return "MP4 video", isobmffSize(data)
+	case isPadding(data):
+		return "Padding", 0
+	case isPrintableText(data):
+		text := strings.TrimSpace(string(data))
+		if len(text) > maxTrailerText {
+			text = text[:maxTrailerText] + "..."
+		}
+		return fmt.Sprintf("Text %q", text), 0
+	}
+	return "Unknown binary data", 0
+}
+
+// isobmffSize returns the size of the top-level boxes at the start of
+// data, stopping at the first box that does not fit
+func isobmffSize(data []byte) int {
+	pos := 0
+	for pos+8 <= len(data) {
+		size := int(binary.BigEndian.Uint32(data[pos:]))
+		switch size {
+		case 0:
+			// The box extends to the end of the file
+			return len(data)
+		case 1:
+			if pos+16 > len(data) {
+				return pos
+			}
+			large := binary.BigEndian.Uint64(data[pos+8:])
+			if large > uint64(len(data)-pos) {
+				return pos
+			}
+			size = int(large)
+		}
+		if size < 8 || pos+size > len(data) {
+			return pos
+		}
+		pos += size
+	}
+	return pos
+}
+
+// samsungTrailer describes a Samsung SEFT trailer at the end of the file
+// The file ends with the directory length and "SEFT"; the directory starts
+// with "SEFH", a version and the entry count, each entry giving the type,
+// the distance of its block back from the directory and its length
+// It returns the description and the offset of the first block
+func samsungTrailer(file []byte, end int) (string, int) {
+	n := len(file)
+	if n-end < 16 || string(file[n-4:]) != "SEFT" {
+		return "", 0
+	}
+	dirLen := int(binary.LittleEndian.Uint32(file[n-8:]))
+	dir := n - 8 - dirLen
+	if dirLen < 12 || dir < end || string(file[dir:dir+4]) != "SEFH" {
+		return "", 0
+	}
+
+	count := int(binary.LittleEndian.Uint32(file[dir+8:]))
+	start := dir
+	var names []string
+	for i := 0; i < count; i++ {
+		entry := dir + 12 + i*12
+		if entry+12 > n-8 {
+			break
+		}
+		blockType := int(binary.LittleEndian.Uint16(file[entry+2:]))
+		back := int(binary.LittleEndian.Uint32(file[entry+4:]))
+		block := dir - back
+		if back <= 0 || block < end {
+			continue
+		}
+		if block < start {
+			start = block
+		}
+
+		// Blocks start with the type and a length-prefixed name
+		name := ""
+		if block+8 <= dir {
+			nameLen := int(binary.LittleEndian.Uint32(file[block+4:]))
+			if nameLen > 0 && nameLen < 64 && block+8+nameLen <= dir {
+				name = string(file[block+8 : block+8+nameLen])
+			}
+		}
+		if name == "" {
+			name = samsungTrailerNames[blockType]
+		}
+		if name == "" {
+			name = fmt.Sprintf("0x%04x", blockType)
+		}
+		names = append(names, name)
+	}
+	value := fmt.Sprintf("Samsung SEFT trailer, %d bytes", n-start)
+	if len(names) > 0 {
+		value += ": " + strings.Join(names, ", ")
+	}
+	return value, start
+}
+
+// isPadding reports whether data holds only zero (or 0xFF) bytes
+func isPadding(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	for _, b := range data {
+		if b != data[0] {
+			return false
+		}
+	}
+	return data[0] == 0x00 || data[0] == 0xFF
+}
@@ -11,13 +11,13 @@ type WebPParser struct {
 }
 
 // Parse extracts EXIF/metadata from WebP images
-func (p *WebPParser) Parse(data []byte) (ExifData, error) {
+func (p *WebPParser) Parse(data []byte) (*Metadata, error) {
 	// Initialize embedded parser
 	if p.exifParser == nil {
 		p.exifParser = &SimpleExifParser{}
 	}
 
-	exifData := make(ExifData)
+	md := NewMetadata(FormatWebP)
 
 	// Verify WebP signature (RIFF....WEBP)
 	if len(data) < 12 {
@@ -62,14 +62,13 @@ func (p *WebPParser) Parse(data []byte) (ExifData, error) {
 		case "EXIF":
 			// WebP EXIF chunk contains raw TIFF data (same as JPEG APP1 EXIF)
 			// Parse using existing TIFF parser
-			if err := p.exifParser.ParseTIFF(chunkData, exifData); err != nil {
-				exifData["EXIF_ParseError"] = err.Error()
+			if err := p.exifParser.ParseTIFF(chunkData, offset, chunkID, md); err != nil {
+				md.Set(GroupErrors, "EXIF_ParseError", err.Error(), offset, chunkID)
 			}
 
 		case "XMP ":
 			// XMP metadata (XML format)
-			xmpData := string(chunkData)
-			exifData["XMP_Metadata"] = xmpData
+			reportXMP(md, chunkData, offset, chunkID)
 
 		case "VP8X":
 			// Extended header - contains feature flags
@@ -99,19 +98,19 @@ func (p *WebPParser) Parse(data []byte) (ExifData, error) {
 				}
 
 				if features != "" {
-					exifData["WebP_Features"] = features
+					md.Set(GroupWebP, "Features", features, offset, chunkID)
 				}
 
 				// Canvas dimensions (24-bit, 1-based)
 				canvasWidth := (uint32(chunkData[4]) | uint32(chunkData[5])<<8 | uint32(chunkData[6])<<16) + 1
 				canvasHeight := (uint32(chunkData[7]) | uint32(chunkData[8])<<8 | uint32(chunkData[9])<<16) + 1
-				exifData["WebP_Canvas_Width"] = fmt.Sprintf("%d", canvasWidth)
-				exifData["WebP_Canvas_Height"] = fmt.Sprintf("%d", canvasHeight)
+				md.SetRaw(GroupWebP, "Canvas_Width", canvasWidth, fmt.Sprintf("%d", canvasWidth), offset, chunkID)
+				md.SetRaw(GroupWebP, "Canvas_Height", canvasHeight, fmt.Sprintf("%d", canvasHeight), offset, chunkID)
 			}
 
 		case "ICCP":
 			// ICC color profile
-			exifData["ICC_Profile"] = fmt.Sprintf("present (%d bytes)", len(chunkData))
+			parseICCProfile(md, chunkData, offset, chunkID)
 
 		case "ANIM":
 			// Animation parameters
@@ -119,16 +118,20 @@ func (p *WebPParser) Parse(data []byte) (ExifData, error) {
 				bgColor := uint32(chunkData[0]) | uint32(chunkData[1])<<8 |
 				           uint32(chunkData[2])<<16 | uint32(chunkData[3])<<24
 				loopCount := uint16(chunkData[4]) | uint16(chunkData[5])<<8
-				exifData["WebP_Animation_BgColor"] = fmt.Sprintf("0x%08X", bgColor)
-				exifData["WebP_Animation_LoopCount"] = fmt.Sprintf("%d", loopCount)
+				md.Set(GroupWebP, "Animation_BgColor", fmt.Sprintf("0x%08X", bgColor), offset, chunkID)
+				md.SetRaw(GroupWebP, "Animation_LoopCount", loopCount, fmt.Sprintf("%d", loopCount), offset, chunkID)
 			}
 
+		case "C2PA":
+			// C2PA manifest store (JUMBF)
+			reportC2PA(md, data, chunkData, offset, chunkID)
+
 		case "VP8 ", "VP8L":
 			// Image data chunks - record format type
 			if chunkID == "VP8 " {
-				exifData["WebP_Format"] = "Lossy (VP8)"
+				md.Set(GroupWebP, "Format", "Lossy (VP8)", offset, chunkID)
 			} else {
-				exifData["WebP_Format"] = "Lossless (VP8L)"
+				md.Set(GroupWebP, "Format", "Lossless (VP8L)", offset, chunkID)
 			}
 		}
 
@@ -139,12 +142,15 @@ func (p *WebPParser) Parse(data []byte) (ExifData, error) {
 		}
 	}
 
+	// The RIFF size covers the whole WebP file; anything after it is a trailer
+	reportTrailer(md, data, int(fileSize)+8+int(fileSize%2), "RIFF")
+
 	// If no metadata found, return basic info
-	if len(exifData) == 0 {
+	if md.Len() == 0 {
 		return nil, fmt.Errorf("no metadata found in WebP file")
 	}
 
-	return exifData, nil
+	return md, nil
 }
 
 // SupportsFormat checks if this parser supports the given format
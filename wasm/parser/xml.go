@@ -0,0 +1,337 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A minimal namespace-aware XML reader for XMP packets
+// encoding/xml relies on reflection that TinyGo only partly supports, and
+// XMP needs no more than elements, attributes, text and namespaces
+
+// maxXMLDepth bounds element nesting
+const maxXMLDepth = 64
+
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// xmlAttr is an attribute with its namespace resolved
+type xmlAttr struct {
+	Space  string
+	Prefix string
+	Local  string
+	Value  string
+}
+
+// xmlNode is an element with its namespace resolved
+// Text concatenates the character data directly inside the element
+type xmlNode struct {
+	Space    string
+	Prefix   string
+	Local    string
+	Attrs    []xmlAttr
+	Children []*xmlNode
+	Text     string
+}
+
+// attr returns the value of the attribute in namespace space
+func (n *xmlNode) attr(space, local string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Space == space && a.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// is reports whether the element has the given namespace and local name
+func (n *xmlNode) is(space, local string) bool {
+	return n.Space == space && n.Local == local
+}
+
+// qualifiedName returns the element name as written
+func (n *xmlNode) qualifiedName() string {
+	if n.Prefix == "" {
+		return n.Local
+	}
+	return n.Prefix + ":" + n.Local
+}
+
+// xmlScope is one level of namespace declarations
+type xmlScope struct {
+	prefixes map[string]string
+	parent   *xmlScope
+}
+
+// resolve returns the namespace URI bound to prefix
+func (s *xmlScope) resolve(prefix string) string {
+	if prefix == "xml" {
+		return xmlNamespace
+	}
+	for ; s != nil; s = s.parent {
+		if uri, ok := s.prefixes[prefix]; ok {
+			return uri
+		}
+	}
+	return ""
+}
+
+// parseXML reads a document into a tree. The returned node is a synthetic
+// root whose children are the top-level elements
+func parseXML(doc string) (*xmlNode, error) {
+	root := &xmlNode{}
+	stack := []*xmlNode{root}
+	scopes := []*xmlScope{nil}
+
+	doc = strings.TrimPrefix(doc, "\xEF\xBB\xBF")
+	pos := 0
+	for pos < len(doc) {
+		lt := strings.IndexByte(doc[pos:], '<')
+		if lt < 0 {
+			break
+		}
+		if lt > 0 && len(stack) > 1 {
+			stack[len(stack)-1].Text += decodeXMLEntities(doc[pos : pos+lt])
+		}
+		pos += lt
+		rest := doc[pos:]
+
+		switch {
+		case strings.HasPrefix(rest, "<?"):
+			end := strings.Index(rest, "?>")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated processing instruction")
+			}
+			pos += end + 2
+
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest, "-->")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			pos += end + 3
+
+		case strings.HasPrefix(rest, "<![CDATA["):
+			end := strings.Index(rest, "]]>")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated CDATA section")
+			}
+			if len(stack) > 1 {
+				stack[len(stack)-1].Text += rest[9:end]
+			}
+			pos += end + 3
+
+		case strings.HasPrefix(rest, "<!"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated declaration")
+			}
+			pos += end + 1
+
+		case strings.HasPrefix(rest, "</"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated end tag")
+			}
+			name := strings.TrimSpace(rest[2:end])
+			top := stack[len(stack)-1]
+			if len(stack) == 1 || name != top.qualifiedName() {
+				return nil, fmt.Errorf("unexpected end tag %s", name)
+			}
+			stack = stack[:len(stack)-1]
+			scopes = scopes[:len(scopes)-1]
+			pos += end + 1
+
+		default:
+			node, scope, selfClosing, n, err := parseXMLStartTag(rest, scopes[len(scopes)-1])
+			if err != nil {
+				return nil, err
+			}
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+			if !selfClosing {
+				if len(stack) > maxXMLDepth {
+					return nil, fmt.Errorf("XML nesting too deep")
+				}
+				stack = append(stack, node)
+				scopes = append(scopes, scope)
+			}
+			pos += n
+		}
+	}
+
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("unclosed element %s", stack[len(stack)-1].Local)
+	}
+	return root, nil
+}
+
+// parseXMLStartTag reads a start tag at the beginning of s and returns the
+// element, its namespace scope and the number of bytes consumed
+func parseXMLStartTag(s string, parent *xmlScope) (*xmlNode, *xmlScope, bool, int, error) {
+	pos := 1
+	name := xmlName(s[pos:])
+	if name == "" {
+		return nil, nil, false, 0, fmt.Errorf("invalid start tag")
+	}
+	pos += len(name)
+
+	type rawAttr struct{ name, value string }
+	var attrs []rawAttr
+	scope := &xmlScope{parent: parent}
+	selfClosing := false
+
+	for {
+		for pos < len(s) && isXMLSpace(s[pos]) {
+			pos++
+		}
+		if pos >= len(s) {
+			return nil, nil, false, 0, fmt.Errorf("unterminated start tag %s", name)
+		}
+		if s[pos] == '>' {
+			pos++
+			break
+		}
+		if strings.HasPrefix(s[pos:], "/>") {
+			pos += 2
+			selfClosing = true
+			break
+		}
+
+		attrName := xmlName(s[pos:])
+		if attrName == "" {
+			return nil, nil, false, 0, fmt.Errorf("invalid attribute in %s", name)
+		}
+		pos += len(attrName)
+		for pos < len(s) && isXMLSpace(s[pos]) {
+			pos++
+		}
+		if pos >= len(s) || s[pos] != '=' {
+			return nil, nil, false, 0, fmt.Errorf("attribute %s has no value", attrName)
+		}
+		pos++
+		for pos < len(s) && isXMLSpace(s[pos]) {
+			pos++
+		}
+		if pos >= len(s) || (s[pos] != '"' && s[pos] != '\'') {
+			return nil, nil, false, 0, fmt.Errorf("attribute %s is not quoted", attrName)
+		}
+		end := strings.IndexByte(s[pos+1:], s[pos])
+		if end < 0 {
+			return nil, nil, false, 0, fmt.Errorf("unterminated attribute %s", attrName)
+		}
+		value := decodeXMLEntities(s[pos+1 : pos+1+end])
+		pos += end + 2
+
+		switch {
+		case attrName == "xmlns":
+			scope.declare("", value)
+		case strings.HasPrefix(attrName, "xmlns:"):
+			scope.declare(attrName[6:], value)
+		default:
+			attrs = append(attrs, rawAttr{attrName, value})
+		}
+	}
+
+	if scope.prefixes == nil {
+		scope = parent
+	}
+	node := &xmlNode{}
+	node.Prefix, node.Local = splitXMLName(name)
+	node.Space = scope.resolve(node.Prefix)
+	for _, a := range attrs {
+		prefix, local := splitXMLName(a.name)
+		space := ""
+		if prefix != "" {
+			// Unprefixed attributes have no namespace
+			space = scope.resolve(prefix)
+		}
+		node.Attrs = append(node.Attrs, xmlAttr{Space: space, Prefix: prefix, Local: local, Value: a.value})
+	}
+	return node, scope, selfClosing, pos, nil
+}
+
+// declare binds a prefix in this scope
+func (s *xmlScope) declare(prefix, uri string) {
+	if s.prefixes == nil {
+		s.prefixes = make(map[string]string)
+	}
+	s.prefixes[prefix] = uri
+}
+
+// xmlName returns the name at the start of s
+func xmlName(s string) string {
+	end := 0
+	for end < len(s) {
+		c := s[end]
+		if isXMLSpace(c) || c == '=' || c == '>' || c == '/' || c == '<' || c == '"' || c == '\'' {
+			break
+		}
+		end++
+	}
+	return s[:end]
+}
+
+// splitXMLName splits "prefix:local"
+func splitXMLName(name string) (string, string) {
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+func isXMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// decodeXMLEntities replaces the predefined and numeric character
+// references; unknown entities are kept as written
+func decodeXMLEntities(s string) string {
+	if !strings.Contains(s, "&") {
+		return s
+	}
+
+	var b strings.Builder
+	for {
+		amp := strings.IndexByte(s, '&')
+		if amp < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:amp])
+		s = s[amp:]
+
+		semi := strings.IndexByte(s, ';')
+		if semi < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		entity := s[1:semi]
+		switch {
+		case entity == "lt":
+			b.WriteByte('<')
+		case entity == "gt":
+			b.WriteByte('>')
+		case entity == "amp":
+			b.WriteByte('&')
+		case entity == "quot":
+			b.WriteByte('"')
+		case entity == "apos":
+			b.WriteByte('\'')
+		case strings.HasPrefix(entity, "#x"), strings.HasPrefix(entity, "#"):
+			digits, base := entity[1:], 10
+			if strings.HasPrefix(entity, "#x") {
+				digits, base = entity[2:], 16
+			}
+			code, err := strconv.ParseUint(digits, base, 32)
+			if err != nil {
+				b.WriteString(s[:semi+1])
+			} else {
+				b.WriteRune(rune(code))
+			}
+		default:
+			b.WriteString(s[:semi+1])
+		}
+		s = s[semi+1:]
+	}
+}
@@ -0,0 +1,307 @@
+package parser
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const rdfNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+// xmpPrefixes maps well-known namespace URIs to their customary prefix,
+// so properties are reported under the same name whatever prefix the
+// writer declared
+var xmpPrefixes = map[string]string{
+	"http://purl.org/dc/elements/1.1/":                     "dc",
+	"http://ns.adobe.com/xap/1.0/":                         "xmp",
+	"http://ns.adobe.com/xap/1.0/mm/":                      "xmpMM",
+	"http://ns.adobe.com/xap/1.0/rights/":                  "xmpRights",
+	"http://ns.adobe.com/xap/1.0/bj/":                      "xmpBJ",
+	"http://ns.adobe.com/xap/1.0/t/pg/":                    "xmpTPg",
+	"http://ns.adobe.com/xap/1.0/g/":                       "xmpG",
+	"http://ns.adobe.com/xap/1.0/g/img/":                   "xmpGImg",
+	"http://ns.adobe.com/xap/1.0/sType/ResourceEvent#":     "stEvt",
+	"http://ns.adobe.com/xap/1.0/sType/ResourceRef#":       "stRef",
+	"http://ns.adobe.com/xap/1.0/sType/Dimensions#":        "stDim",
+	"http://ns.adobe.com/xap/1.0/sType/Version#":           "stVer",
+	"http://ns.adobe.com/xmp/1.0/DynamicMedia/":            "xmpDM",
+	"http://ns.adobe.com/xmp/note/":                        "xmpNote",
+	"http://ns.adobe.com/photoshop/1.0/":                   "photoshop",
+	"http://ns.adobe.com/camera-raw-settings/1.0/":         "crs",
+	"http://ns.adobe.com/lightroom/1.0/":                   "lr",
+	"http://ns.adobe.com/tiff/1.0/":                        "tiff",
+	"http://ns.adobe.com/exif/1.0/":                        "exif",
+	"http://ns.adobe.com/exif/1.0/aux/":                    "aux",
+	"http://cipa.jp/exif/1.0/":                             "exifEX",
+	"http://ns.adobe.com/pdf/1.3/":                         "pdf",
+	"http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/":          "Iptc4xmpCore",
+	"http://iptc.org/std/Iptc4xmpExt/2008-02-29/":          "Iptc4xmpExt",
+	"http://ns.useplus.org/ldf/xmp/1.0/":                   "plus",
+	"http://www.metadataworkinggroup.com/schemas/regions/": "mwg-rs",
+	"http://ns.adobe.com/xmp/sType/Area#":                  "stArea",
+	"http://ns.microsoft.com/photo/1.0/":                   "MicrosoftPhoto",
+	"http://ns.google.com/photos/1.0/panorama/":            "GPano",
+	"http://ns.google.com/photos/1.0/image/":               "GImage",
+	"http://ns.google.com/photos/1.0/depthmap/":            "GDepth",
+	"http://ns.google.com/photos/1.0/camera/":              "GCamera",
+	"http://ns.google.com/photos/1.0/container/":           "Container",
+	"http://ns.google.com/photos/1.0/container/item/":      "Item",
+	"http://ns.adobe.com/hdr-gain-map/1.0/":                "hdrgm",
+	"http://ns.apple.com/faceinfo/1.0/":                    "apple-fi",
+	"http://ns.apple.com/HDRGainMap/1.0/":                  "HDRGainMap",
+	"http://www.dji.com/drone-dji/1.0/":                    "drone-dji",
+}
+
+// xmpProperty is one leaf value of an XMP packet
+// Path uses the XMP path notation: array items as "dc:creator[1]",
+// language alternatives as "dc:title[x-default]" and struct fields as
+// "xmpMM:DerivedFrom/stRef:documentID"
+type xmpProperty struct {
+	Path  string
+	Value string
+}
+
+// parseXMP flattens the RDF properties of an XMP packet
+func parseXMP(packet []byte) ([]xmpProperty, error) {
+	root, err := parseXML(string(packet))
+	if err != nil {
+		return nil, err
+	}
+
+	rdf := findRDF(root)
+	if rdf == nil {
+		return nil, fmt.Errorf("no rdf:RDF element")
+	}
+
+	x := &xmpWalker{}
+	for _, description := range rdf.Children {
+		// rdf:Description, or a typed node used in its place
+		x.fields("", description)
+	}
+	return x.properties, nil
+}
+
+// findRDF returns the first rdf:RDF element in the tree
+func findRDF(node *xmlNode) *xmlNode {
+	if node.is(rdfNamespace, "RDF") {
+		return node
+	}
+	for _, child := range node.Children {
+		if rdf := findRDF(child); rdf != nil {
+			return rdf
+		}
+	}
+	return nil
+}
+
+// xmpWalker collects the leaf properties of an RDF tree
+type xmpWalker struct {
+	properties []xmpProperty
+}
+
+func (x *xmpWalker) emit(path, value string) {
+	x.properties = append(x.properties, xmpProperty{Path: path, Value: value})
+}
+
+// fields reports the attributes and child elements of a node as the
+// fields of the struct at path ("" for the top level)
+func (x *xmpWalker) fields(path string, node *xmlNode) {
+	for _, a := range node.Attrs {
+		if a.Space == rdfNamespace || a.Space == xmlNamespace {
+			continue
+		}
+		x.emit(joinXMPPath(path, xmpName(a.Space, a.Prefix, a.Local)), a.Value)
+	}
+
+	for _, child := range node.Children {
+		switch {
+		case child.is(rdfNamespace, "value"):
+			// rdf:value carries the value of a qualified property
+			x.property(path, child)
+		case child.is(rdfNamespace, "Description"):
+			x.fields(path, child)
+		default:
+			x.property(joinXMPPath(path, xmpName(child.Space, child.Prefix, child.Local)), child)
+		}
+	}
+}
+
+// property reports a property element: a simple value, a URI resource,
+// an array or a struct
+func (x *xmpWalker) property(path string, node *xmlNode) {
+	if resource, ok := node.attr(rdfNamespace, "resource"); ok {
+		x.emit(path, resource)
+		return
+	}
+	if parseType, _ := node.attr(rdfNamespace, "parseType"); parseType == "Resource" {
+		x.fields(path, node)
+		return
+	}
+
+	if len(node.Children) == 1 {
+		child := node.Children[0]
+		if child.Space == rdfNamespace && (child.Local == "Seq" || child.Local == "Bag" || child.Local == "Alt") {
+			x.array(path, child)
+			return
+		}
+	}
+
+	if len(node.Children) > 0 || xmpHasFields(node) {
+		// Struct, either as nested elements or in the shorthand form
+		// where the fields are attributes of the property element
+		x.fields(path, node)
+		return
+	}
+	x.emit(path, strings.TrimSpace(node.Text))
+}
+
+// array reports the items of an rdf:Seq, rdf:Bag or rdf:Alt
+// Items of a language alternative are keyed by their xml:lang
+func (x *xmpWalker) array(path string, container *xmlNode) {
+	index := 0
+	for _, item := range container.Children {
+		if !item.is(rdfNamespace, "li") {
+			continue
+		}
+		index++
+
+		itemPath := fmt.Sprintf("%s[%d]", path, index)
+		if lang, ok := item.attr(xmlNamespace, "lang"); ok && container.Local == "Alt" {
+			itemPath = path + "[" + lang + "]"
+		}
+		x.property(itemPath, item)
+	}
+}
+
+// xmpHasFields reports whether an element carries struct fields as
+// attributes
+func xmpHasFields(node *xmlNode) bool {
+	for _, a := range node.Attrs {
+		if a.Space != rdfNamespace && a.Space != xmlNamespace {
+			return true
+		}
+	}
+	return false
+}
+
+// xmpName returns the qualified name of a property, preferring the
+// customary prefix of its namespace
+func xmpName(space, prefix, local string) string {
+	if known, ok := xmpPrefixes[space]; ok {
+		prefix = known
+	}
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+func joinXMPPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "/" + name
+}
+
+// reportXMP parses an XMP packet into the XMP group
+// If the packet is not well-formed it is kept as a single string
+func reportXMP(md *Metadata, packet []byte, offset int, source string) {
+	properties, err := parseXMP(packet)
+	if err != nil {
+		md.Set(GroupXMP, "Metadata", string(packet), offset, source)
+		md.Set(GroupErrors, "XMP_ParseError", err.Error(), offset, source)
+		return
+	}
+	for _, prop := range properties {
+		md.Set(GroupXMP, prop.Path, prop.Value, offset, source)
+	}
+}
+
+// xmpExtensionSignature starts the JPEG APP1 segments carrying extended
+// XMP, the part of a packet that does not fit in the 64 KB main segment
+const xmpExtensionSignature = "http://ns.adobe.com/xmp/extension/\x00"
+
+// xmpExtension is one segment of extended XMP: a chunk of the packet
+// whose MD5 digest (as 32 hex digits) is the GUID, at the given offset
+type xmpExtension struct {
+	guid          string
+	fullLength    int
+	offset        int
+	data          []byte
+	segmentOffset int
+}
+
+// parseXMPExtension reads the header of an extended XMP segment
+func parseXMPExtension(segment []byte, segmentOffset int) (xmpExtension, bool) {
+	header := len(xmpExtensionSignature)
+	if len(segment) < header+40 || string(segment[:header]) != xmpExtensionSignature {
+		return xmpExtension{}, false
+	}
+	return xmpExtension{
+		guid:          string(segment[header : header+32]),
+		fullLength:    int(binary.BigEndian.Uint32(segment[header+32 : header+36])),
+		offset:        int(binary.BigEndian.Uint32(segment[header+36 : header+40])),
+		data:          segment[header+40:],
+		segmentOffset: segmentOffset,
+	}, true
+}
+
+// reportExtendedXMP reassembles the extended XMP referenced by the main
+// packet's xmpNote:HasExtendedXMP, checks its MD5 digest and merges its
+// properties into the XMP group. Extensions with another GUID are ignored,
+// as the specification requires
+func reportExtendedXMP(md *Metadata, parts []xmpExtension, source string) {
+	expected := ""
+	if e := md.Get(GroupXMP, "xmpNote:HasExtendedXMP"); e != nil {
+		expected = e.Value
+	}
+
+	var guids []string
+	byGUID := make(map[string][]xmpExtension)
+	for _, part := range parts {
+		if _, seen := byGUID[part.guid]; !seen {
+			guids = append(guids, part.guid)
+		}
+		byGUID[part.guid] = append(byGUID[part.guid], part)
+	}
+
+	for _, guid := range guids {
+		chunks := byGUID[guid]
+		first := chunks[0].segmentOffset
+		if !strings.EqualFold(guid, expected) {
+			md.Set(GroupErrors, "XMP_ExtendedError", fmt.Sprintf("extended XMP %s is not referenced by xmpNote:HasExtendedXMP", guid), first, source)
+			continue
+		}
+
+		// Order the chunks by offset (segments may be stored in any order)
+		for i := 1; i < len(chunks); i++ {
+			for j := i; j > 0 && chunks[j].offset < chunks[j-1].offset; j-- {
+				chunks[j], chunks[j-1] = chunks[j-1], chunks[j]
+			}
+		}
+
+		fullLength := chunks[0].fullLength
+		packet := make([]byte, 0, len(chunks[0].data)*len(chunks))
+		for _, chunk := range chunks {
+			if chunk.fullLength != fullLength || chunk.offset != len(packet) {
+				break
+			}
+			packet = append(packet, chunk.data...)
+		}
+		if len(packet) != fullLength {
+			md.Set(GroupErrors, "XMP_ExtendedError", fmt.Sprintf("extended XMP %s is incomplete (%d of %d bytes)", guid, len(packet), fullLength), first, source)
+			continue
+		}
+
+		digest := md5.Sum(packet)
+		if !strings.EqualFold(hex.EncodeToString(digest[:]), guid) {
+			md.Set(GroupErrors, "XMP_ExtendedError", fmt.Sprintf("extended XMP %s does not match its MD5 digest", guid), first, source)
+			continue
+		}
+
+		md.Set(GroupXMP, "ExtendedXMP", fmt.Sprintf("%s (%d bytes in %d segments, MD5 verified)", guid, fullLength, len(chunks)), first, source)
+		reportXMP(md, packet, first, source)
+	}
+}